@@ -11,10 +11,27 @@ type TaskConfigRun struct {
 	Args []string `json:"args" yaml:"args"`
 }
 
+// ContainerLimits have cpu as a number of CPUs, and memory in bytes.
+type ContainerLimits struct {
+	CPU    float64 `json:"cpu"    validate:"gte=0" yaml:"cpu"`
+	Memory int64   `json:"memory" validate:"gte=0" yaml:"memory"`
+	Pids   int64   `json:"pids"   validate:"gte=0" yaml:"pids"`
+}
+
+// TaskConfigInput is mounted at its path, or `/<name>` by default.
+// With a host path, such as `.` for the checkout, the host directory is mounted read-only.
+type TaskConfigInput struct {
+	Name     string `json:"name"      validate:"required" yaml:"name"`
+	Path     string `json:"path"      yaml:"path"`
+	HostPath string `json:"host_path" yaml:"host_path"`
+}
+
 type TaskConfig struct {
-	Platform      string        `json:"platform"       validate:"oneof='linux' 'darwin' 'windows'" yaml:"platform"`
-	ImageResource ImageResource `json:"image_resource" yaml:"image_resource"`
-	Run           TaskConfigRun `json:"run"            validate:"required"                         yaml:"run"`
+	Platform        string            `json:"platform"         validate:"oneof='linux' 'darwin' 'windows'" yaml:"platform"`
+	ImageResource   ImageResource     `json:"image_resource"   yaml:"image_resource"`
+	ContainerLimits ContainerLimits   `json:"container_limits" yaml:"container_limits"`
+	Inputs          []TaskConfigInput `json:"inputs"           validate:"dive"                             yaml:"inputs"`
+	Run             TaskConfigRun     `json:"run"              validate:"required"                         yaml:"run"`
 }
 
 type Step struct {
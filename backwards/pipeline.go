@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 
 	"github.com/go-playground/validator/v10"
@@ -39,10 +40,35 @@ func NewPipeline(filename string) (string, error) {
 		return "", fmt.Errorf("could not marshal pipeline: %w", err)
 	}
 
-	slog.Info("pipeline", "contents", string(contents))
+	slog.Info("pipeline", "contents", redacted(config))
 	pipeline := "const config = " + string(contents) + ";\n" +
 		pipelineJS +
-		"\n; const pipeline = createPipeline(config); export { pipeline };"
+		"\n; const pipeline = createPipeline(config); const images = collectImages(config); export { images, pipeline };"
 
 	return pipeline, nil
 }
+
+// redacted is a copy of the config without registry passwords, for logging.
+func redacted(config Config) Config {
+	jobs := make(Jobs, 0, len(config.Jobs))
+
+	for _, job := range config.Jobs {
+		plan := make(Steps, 0, len(job.Plan))
+
+		for _, step := range job.Plan {
+			if _, ok := step.Config.ImageResource.Source["password"]; ok {
+				step.Config.ImageResource.Source = maps.Clone(step.Config.ImageResource.Source)
+				step.Config.ImageResource.Source["password"] = "[redacted]"
+			}
+
+			plan = append(plan, step)
+		}
+
+		job.Plan = plan
+		jobs = append(jobs, job)
+	}
+
+	config.Jobs = jobs
+
+	return config
+}
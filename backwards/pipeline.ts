@@ -17,6 +17,18 @@ function createPipeline(config: PipelineConfig) {
       name: task.task,
       image: task.config.image_resource.source.repository,
       command: [task.config.run.path].concat(task.config.run.args),
+      resources: task.config.container_limits,
+      registry_auth: task.config.image_resource.source.username
+        ? {
+          username: task.config.image_resource.source.username,
+          password: task.config.image_resource.source.password,
+        }
+        : undefined,
+      mounts: (task.config.inputs ?? []).map((input) => ({
+        name: input.name,
+        path: input.path || `/${input.name}`,
+        host_path: input.host_path,
+      })),
     });
     console.log(JSON.stringify(result, null, 2));
     if (task.assert.stdout != "") {
@@ -30,3 +42,17 @@ function createPipeline(config: PipelineConfig) {
     }
   };
 }
+
+function collectImages(config: PipelineConfig): ImageConfig[] {
+  return config.jobs.flatMap((job) =>
+    job.plan.map((step) => ({
+      image: step.config.image_resource.source.repository,
+      registry_auth: step.config.image_resource.source.username
+        ? {
+          username: step.config.image_resource.source.username,
+          password: step.config.image_resource.source.password,
+        }
+        : undefined,
+    }))
+  );
+}
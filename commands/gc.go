@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/registry"
+)
+
+type GC struct {
+	DryRun       bool          `help:"only list the resources that would be removed"`
+	KeepFor      time.Duration `default:"24h"                                         help:"how long the containers of failed tasks are kept, so they can be hijacked"`
+	OlderThan    time.Duration `default:"0s"                                          help:"only remove resources created longer ago than this"`
+	Orchestrator string        `default:"native"                                      help:"orchestrator runtime to collect, optionally configured as a URL"`
+	Registry     string        `help:"directory active runs are recorded in (default: user cache dir)" type:"path"`
+}
+
+func (c *GC) Run() error {
+	driverName, options, err := orchestra.ParseURL(c.Orchestrator, "gc")
+	if err != nil {
+		return fmt.Errorf("could not parse orchestrator: %w", err)
+	}
+
+	orchestrator, found := orchestra.Get(driverName)
+	if !found {
+		return fmt.Errorf("could not get orchestrator (%q): %w", driverName, ErrOrchestratorNotFound)
+	}
+
+	client, err := orchestrator(options)
+	if err != nil {
+		return fmt.Errorf("could not create %s client: %w", driverName, err)
+	}
+	defer client.Close()
+
+	collector, ok := client.(orchestra.Collector)
+	if !ok {
+		return fmt.Errorf("could not collect %s: %w", driverName, ErrCollectorNotSupported)
+	}
+
+	active, err := c.activeNamespaces()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	resources, err := collector.Resources(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list resources: %w", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer writer.Flush()
+
+	_, _ = fmt.Fprintln(writer, "KIND\tID\tNAMESPACE\tOWNER\tAGE")
+
+	for _, resource := range resources {
+		age := time.Since(resource.CreatedAt)
+
+		if resource.Owner.IsAlive() || active[resource.Namespace] || age < c.OlderThan {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(
+			writer, "%s\t%s\t%s\t%s\t%s\n",
+			resource.Kind, resource.ID, resource.Namespace, resource.Owner, age.Round(time.Second),
+		)
+
+		if c.DryRun {
+			continue
+		}
+
+		err := collector.RemoveResource(ctx, resource)
+		if err != nil {
+			slog.Error("gc.remove", "kind", resource.Kind, "id", resource.ID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// activeNamespaces are the namespaces of registered runs whose process is still alive,
+// or that kept containers more recently than KeepFor, so they can still be hijacked.
+// Other runs are unregistered.
+func (c *GC) activeNamespaces() (map[string]bool, error) {
+	runs, err := registry.New(c.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("could not open registry: %w", err)
+	}
+
+	registered, err := runs.Runs()
+	if err != nil {
+		return nil, fmt.Errorf("could not list runs: %w", err)
+	}
+
+	active := map[string]bool{}
+
+	for _, run := range registered {
+		owner, _ := orchestra.ParseOwner(run.Owner)
+		if owner.IsAlive() || (len(run.Kept) > 0 && time.Since(run.StartedAt) < c.KeepFor) {
+			active[run.Namespace] = true
+
+			continue
+		}
+
+		if c.DryRun {
+			continue
+		}
+
+		err := runs.Unregister(run.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not unregister run %s: %w", run.ID, err)
+		}
+	}
+
+	return active, nil
+}
+
+var ErrCollectorNotSupported = errors.New("orchestrator does not support garbage collection")
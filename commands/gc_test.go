@@ -0,0 +1,181 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jtarchie/ci/commands"
+	"github.com/jtarchie/ci/orchestra"
+	_ "github.com/jtarchie/ci/orchestra/native"
+	"github.com/jtarchie/ci/registry"
+	. "github.com/onsi/gomega"
+)
+
+func deadOwner(assert *WithT) orchestra.Owner {
+	command := exec.Command("true")
+	assert.Expect(command.Run()).To(Succeed())
+
+	owner := orchestra.CurrentOwner()
+	owner.PID = command.Process.Pid
+	owner.StartTime = 0
+
+	return owner
+}
+
+// leftBehind creates a directory like a native driver of the owner would have.
+func leftBehind(assert *WithT, base, namespace string, owner orchestra.Owner, createdAt time.Time) string {
+	path := filepath.Join(base, namespace)
+	assert.Expect(os.MkdirAll(path, os.ModePerm)).To(Succeed())
+
+	contents, err := json.Marshal(map[string]any{
+		"created_at": createdAt,
+		"namespace":  namespace,
+		"owner":      owner.String(),
+	})
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(os.WriteFile(filepath.Join(path, ".orchestra.json"), contents, 0o600)).To(Succeed())
+
+	return path
+}
+
+func TestGC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("removes resources of dead owners", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+		base := t.TempDir()
+
+		dead := leftBehind(assert, base, "dead", deadOwner(assert), time.Now())
+		alive := leftBehind(assert, base, "alive", orchestra.CurrentOwner(), time.Now())
+
+		gc := &commands.GC{Orchestrator: "native://" + base, Registry: t.TempDir()}
+		assert.Expect(gc.Run()).To(Succeed())
+
+		assert.Expect(dead).NotTo(BeADirectory())
+		assert.Expect(alive).To(BeADirectory())
+	})
+
+	t.Run("dry run only lists", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+		base := t.TempDir()
+
+		dead := leftBehind(assert, base, "dead", deadOwner(assert), time.Now())
+
+		gc := &commands.GC{DryRun: true, Orchestrator: "native://" + base, Registry: t.TempDir()}
+		assert.Expect(gc.Run()).To(Succeed())
+
+		assert.Expect(dead).To(BeADirectory())
+	})
+
+	t.Run("older than", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+		base := t.TempDir()
+
+		recent := leftBehind(assert, base, "recent", deadOwner(assert), time.Now())
+		old := leftBehind(assert, base, "old", deadOwner(assert), time.Now().Add(-2*time.Hour))
+
+		gc := &commands.GC{OlderThan: time.Hour, Orchestrator: "native://" + base, Registry: t.TempDir()}
+		assert.Expect(gc.Run()).To(Succeed())
+
+		assert.Expect(recent).To(BeADirectory())
+		assert.Expect(old).NotTo(BeADirectory())
+	})
+
+	t.Run("keeps namespaces of active runs", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+		base := t.TempDir()
+
+		active := leftBehind(assert, base, "active", deadOwner(assert), time.Now())
+
+		registryDir := t.TempDir()
+
+		runs, err := registry.New(registryDir)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = runs.Register(registry.Run{
+			ID:        "active",
+			Namespace: "active",
+			Owner:     orchestra.CurrentOwner().String(),
+			StartedAt: time.Now(),
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = runs.Register(registry.Run{
+			ID:        "dead",
+			Namespace: "dead",
+			Owner:     deadOwner(assert).String(),
+			StartedAt: time.Now(),
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		gc := &commands.GC{Orchestrator: "native://" + base, Registry: registryDir}
+		assert.Expect(gc.Run()).To(Succeed())
+
+		assert.Expect(active).To(BeADirectory())
+
+		_, err = runs.Get("active")
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		_, err = runs.Get("dead")
+		assert.Expect(err).To(MatchError(registry.ErrRunNotFound))
+	})
+	t.Run("keeps runs with kept containers for keep-for", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+		base := t.TempDir()
+
+		recent := leftBehind(assert, base, "recent", deadOwner(assert), time.Now().Add(-2*time.Hour))
+		expired := leftBehind(assert, base, "expired", deadOwner(assert), time.Now().Add(-2*time.Hour))
+
+		registryDir := t.TempDir()
+
+		runs, err := registry.New(registryDir)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		kept := []registry.KeptTask{{ContainerID: "container", Name: "failing"}}
+
+		err = runs.Register(registry.Run{
+			ID:        "recent",
+			Kept:      kept,
+			Namespace: "recent",
+			Owner:     deadOwner(assert).String(),
+			StartedAt: time.Now().Add(-2 * time.Hour),
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = runs.Register(registry.Run{
+			ID:        "expired",
+			Kept:      kept,
+			Namespace: "expired",
+			Owner:     deadOwner(assert).String(),
+			StartedAt: time.Now().Add(-48 * time.Hour),
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		// the default of a plain gc, which otherwise removes everything of dead owners
+		gc := &commands.GC{KeepFor: 24 * time.Hour, Orchestrator: "native://" + base, Registry: registryDir}
+		assert.Expect(gc.Run()).To(Succeed())
+
+		assert.Expect(recent).To(BeADirectory())
+		assert.Expect(expired).NotTo(BeADirectory())
+
+		_, err = runs.Get("recent")
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		_, err = runs.Get("expired")
+		assert.Expect(err).To(MatchError(registry.ErrRunNotFound))
+	})
+}
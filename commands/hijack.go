@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/registry"
+)
+
+type Hijack struct {
+	RunID    string `arg:"" help:"ID of the run that kept the container" name:"run"`
+	Task     string `arg:"" help:"name of the failed task"`
+	Keep     bool   `help:"keep the container after the shell exits, so it can be hijacked again"`
+	Registry string `help:"directory active runs are recorded in (default: user cache dir)" type:"path"`
+
+	// the shell uses the standard streams, unless they are set
+	Stdin  io.Reader `kong:"-"`
+	Stdout io.Writer `kong:"-"`
+	Stderr io.Writer `kong:"-"`
+}
+
+func (c *Hijack) Run() error {
+	runs, err := registry.New(c.Registry)
+	if err != nil {
+		return fmt.Errorf("could not open registry: %w", err)
+	}
+
+	run, err := runs.Get(c.RunID)
+	if err != nil {
+		return fmt.Errorf("could not find run: %w", err)
+	}
+
+	containerID := ""
+
+	for _, kept := range run.Kept {
+		if kept.Name == c.Task {
+			containerID = kept.ContainerID
+		}
+	}
+
+	if containerID == "" {
+		return fmt.Errorf("%w: %q in run %s", ErrTaskNotKept, c.Task, run.ID)
+	}
+
+	driverName, options, err := orchestra.ParseURL(run.Orchestrator, run.Namespace)
+	if err != nil {
+		return fmt.Errorf("could not parse orchestrator: %w", err)
+	}
+
+	orchestrator, found := orchestra.Get(driverName)
+	if !found {
+		return fmt.Errorf("could not get orchestrator (%q): %w", driverName, ErrOrchestratorNotFound)
+	}
+
+	options.Attach = true
+	options.Namespace = run.Namespace
+
+	client, err := orchestrator(options)
+	if err != nil {
+		return fmt.Errorf("could not create %s client: %w", driverName, err)
+	}
+	defer client.Close()
+
+	hijacker, ok := client.(orchestra.Hijacker)
+	if !ok {
+		return fmt.Errorf("could not hijack %s: %w", driverName, ErrHijackNotSupported)
+	}
+
+	ctx := context.Background()
+
+	code, err := hijacker.Hijack(ctx, containerID, c.stdin(), c.stdout(), c.stderr())
+	if err != nil {
+		return fmt.Errorf("could not hijack %s: %w", c.Task, err)
+	}
+
+	if !c.Keep {
+		err = c.release(ctx, client, runs, run)
+		if err != nil {
+			return err
+		}
+	}
+
+	if code != 0 {
+		return fmt.Errorf("%w: %d", ErrShellExited, code)
+	}
+
+	return nil
+}
+
+// release forgets the hijacked task once its shell has exited.
+// The resources of the run are removed with the last kept task,
+// until then they are still needed by the others.
+func (c *Hijack) release(ctx context.Context, client orchestra.Driver, runs *registry.Registry, run registry.Run) error {
+	run.Kept = slices.DeleteFunc(run.Kept, func(kept registry.KeptTask) bool {
+		return kept.Name == c.Task
+	})
+
+	if len(run.Kept) > 0 {
+		err := runs.Register(run)
+		if err != nil {
+			return fmt.Errorf("could not release %s: %w", c.Task, err)
+		}
+
+		return nil
+	}
+
+	collector, ok := client.(orchestra.Collector)
+	if !ok {
+		return fmt.Errorf("could not release run %s: %w", run.ID, ErrCollectorNotSupported)
+	}
+
+	resources, err := collector.Resources(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list resources: %w", err)
+	}
+
+	for _, resource := range resources {
+		if resource.Namespace != run.Namespace {
+			continue
+		}
+
+		err := collector.RemoveResource(ctx, resource)
+		if err != nil {
+			return fmt.Errorf("could not remove %s %s: %w", resource.Kind, resource.ID, err)
+		}
+	}
+
+	err = runs.Unregister(run.ID)
+	if err != nil {
+		return fmt.Errorf("could not unregister run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+func (c *Hijack) stdin() io.Reader {
+	if c.Stdin != nil {
+		return c.Stdin
+	}
+
+	return os.Stdin
+}
+
+func (c *Hijack) stdout() io.Writer {
+	if c.Stdout != nil {
+		return c.Stdout
+	}
+
+	return os.Stdout
+}
+
+func (c *Hijack) stderr() io.Writer {
+	if c.Stderr != nil {
+		return c.Stderr
+	}
+
+	return os.Stderr
+}
+
+var (
+	ErrHijackNotSupported = errors.New("orchestrator does not support hijacking")
+	ErrShellExited        = errors.New("shell exited with code")
+	ErrTaskNotKept        = errors.New("task was not kept")
+)
@@ -0,0 +1,98 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jtarchie/ci/commands"
+	"github.com/jtarchie/ci/registry"
+	. "github.com/onsi/gomega"
+)
+
+func TestHijack(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	pipelinePath := filepath.Join(t.TempDir(), "pipeline.js")
+
+	err := os.WriteFile(pipelinePath, []byte(`
+const pipeline = () => {
+  run({ name: "first", command: ["sh", "-c", "exit 1"], env: { TASK: "first" } });
+  run({ name: "second", command: ["sh", "-c", "exit 1"], env: { TASK: "second" } });
+};
+
+export { pipeline };
+`), 0o600)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	pipeline, err := os.Open(pipelinePath)
+	assert.Expect(err).NotTo(HaveOccurred())
+	defer pipeline.Close()
+
+	base := t.TempDir()
+	registryDir := t.TempDir()
+
+	runner := commands.Runner{
+		KeepOnFailure: true,
+		Orchestrator:  "native://" + base,
+		Pipeline:      pipeline,
+		Registry:      registryDir,
+	}
+	assert.Expect(runner.Run()).To(Succeed())
+
+	runs, err := registry.New(registryDir)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	all, err := runs.Runs()
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(all).To(HaveLen(1))
+
+	run := all[0]
+	assert.Expect(run.Kept).To(HaveLen(2))
+
+	hijack := func(task string, keep bool) string {
+		stdout := &strings.Builder{}
+
+		err := (&commands.Hijack{
+			Keep:     keep,
+			Registry: registryDir,
+			RunID:    run.ID,
+			Stderr:   &strings.Builder{},
+			Stdin:    strings.NewReader("echo task=$TASK\nexit 3\n"),
+			Stdout:   stdout,
+			Task:     task,
+		}).Run()
+		assert.Expect(err).To(MatchError(commands.ErrShellExited))
+
+		return stdout.String()
+	}
+
+	// the shell has the environment of the task, and is kept when asked to
+	assert.Expect(hijack("first", true)).To(ContainSubstring("task=first"))
+	assert.Expect(hijack("first", false)).To(ContainSubstring("task=first"))
+
+	released, err := runs.Get(run.ID)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(released.Kept).To(HaveLen(1))
+	assert.Expect(released.Kept[0].Name).To(Equal("second"))
+
+	err = (&commands.Hijack{Registry: registryDir, RunID: run.ID, Task: "first"}).Run()
+	assert.Expect(err).To(MatchError(commands.ErrTaskNotKept))
+
+	dirs, err := os.ReadDir(base)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(dirs).To(HaveLen(1))
+
+	// the last kept task removes the resources of the run
+	assert.Expect(hijack("second", false)).To(ContainSubstring("task=second"))
+
+	_, err = runs.Get(run.ID)
+	assert.Expect(err).To(MatchError(registry.ErrRunNotFound))
+
+	dirs, err = os.ReadDir(base)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(dirs).To(BeEmpty())
+}
@@ -3,18 +3,28 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/evanw/esbuild/pkg/api"
+	"github.com/google/uuid"
 	"github.com/jtarchie/ci/backwards"
 	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/replay"
+	"github.com/jtarchie/ci/registry"
 	"github.com/jtarchie/ci/runtime"
 )
 
 type Runner struct {
-	Pipeline     *os.File `arg:""           help:"Path to pipeline javascript file"`
-	Orchestrator string   `default:"native" help:"orchestrator runtime to use"`
+	Pipeline      *os.File `arg:""           help:"Path to pipeline javascript file"`
+	KeepOnFailure bool     `help:"keep the containers of failed tasks, so they can be hijacked with 'ci hijack'"`
+	Orchestrator  string   `default:"native" help:"orchestrator runtime to use, optionally configured as a URL (e.g. docker://unix:///var/run/docker.sock?network=ci)"`
+	Record        string   `help:"record orchestrator interactions to a fixture file"                                                type:"path"`
+	Registry      string   `help:"directory to record active runs in (default: user cache dir)"                                      type:"path"`
+	Replay        string   `help:"replay orchestrator interactions from a fixture file, instead of using the orchestrator"           type:"path"`
 }
 
 func (c *Runner) Run() error {
@@ -44,27 +54,169 @@ func (c *Runner) Run() error {
 		pipeline = string(result.OutputFiles[0].Contents)
 	}
 
-	orchestrator, found := orchestra.Get(c.Orchestrator)
-	if !found {
-		return fmt.Errorf("could not get orchestrator (%q): %w", c.Orchestrator, ErrOrchestratorNotFound)
+	// host paths in the pipeline are relative to its file
+	dir, err := filepath.Abs(filepath.Dir(c.Pipeline.Name()))
+	if err != nil {
+		return fmt.Errorf("could not resolve pipeline dir: %w", err)
 	}
 
-	client, err := orchestrator("ci")
+	runID, err := uuid.NewV7()
 	if err != nil {
-		return fmt.Errorf("could not create docker client: %w", err)
+		return fmt.Errorf("could not generate run id: %w", err)
+	}
+
+	run := registry.Run{
+		ID:           runID.String(),
+		Orchestrator: c.Orchestrator,
+		Owner:        orchestra.CurrentOwner().String(),
+		Pipeline:     strings.TrimSuffix(filepath.Base(c.Pipeline.Name()), extension),
+		StartedAt:    time.Now(),
+	}
+
+	client, err := c.driver(&run)
+	if err != nil {
+		return err
+	}
+
+	runs, err := registry.New(c.Registry)
+	if err != nil {
+		_ = client.Close()
+
+		return fmt.Errorf("could not open registry: %w", err)
+	}
+
+	err = runs.Register(run)
+	if err != nil {
+		_ = client.Close()
+
+		return fmt.Errorf("could not register run: %w", err)
+	}
+
+	slog.Info("run", "id", run.ID, "namespace", run.Namespace, "pipeline", run.Pipeline)
+
+	// a replay runs no containers, so there is nothing to keep
+	keepOnFailure := c.KeepOnFailure && c.Replay == ""
+	if c.KeepOnFailure && !keepOnFailure {
+		slog.Warn("run.keep", "id", run.ID, "reason", "containers are not kept when replaying")
 	}
-	defer client.Close()
 
 	js := runtime.NewJS()
+	sandbox := runtime.NewPipelineRunner(client, dir, keepOnFailure)
+
+	err = js.Execute(pipeline, sandbox)
+	if err != nil {
+		err = fmt.Errorf("could not execute pipeline: %w", err)
+	}
+
+	for _, summary := range sandbox.Summaries() {
+		slog.Info("run.summary", "task", summary.Name, "state", summary.State, "duration", summary.Duration, "usage", summary.Usage)
+	}
+
+	stopErr := sandbox.Close()
+	if stopErr != nil && err == nil {
+		err = fmt.Errorf("could not stop services: %w", stopErr)
+	}
+
+	// the run stays registered with the kept containers, until they are hijacked or collected
+	if kept := sandbox.Kept(); len(kept) > 0 {
+		return c.keep(client, runs, run, kept, err)
+	}
+
+	unregisterErr := runs.Unregister(run.ID)
+	if unregisterErr != nil {
+		slog.Error("run.unregister", "id", run.ID, "err", unregisterErr)
+	}
+
+	if err != nil {
+		_ = client.Close()
+
+		return err
+	}
 
-	err = js.Execute(pipeline, runtime.NewPipelineRunner(client))
+	// closing can fail a replay that did not consume its fixture
+	err = client.Close()
 	if err != nil {
-		return fmt.Errorf("could not execute pipeline: %w", err)
+		return fmt.Errorf("could not close orchestrator: %w", err)
 	}
 
 	return nil
 }
 
+// keep records the kept containers of a run, the orchestrator is not closed,
+// as that would remove them, but a recording is still written.
+func (c *Runner) keep(
+	client orchestra.Driver,
+	runs *registry.Registry,
+	run registry.Run,
+	kept []runtime.KeptContainer,
+	runErr error,
+) error {
+	if recorder, ok := client.(*replay.Recorder); ok {
+		flushErr := recorder.Flush()
+		if flushErr != nil && runErr == nil {
+			runErr = fmt.Errorf("could not write fixture: %w", flushErr)
+		}
+	}
+
+	for _, container := range kept {
+		run.Kept = append(run.Kept, registry.KeptTask{
+			ContainerID: container.ID,
+			Name:        container.Name,
+		})
+
+		slog.Info("run.kept", "id", run.ID, "task", container.Name, "hijack", fmt.Sprintf("ci hijack %s %s", run.ID, container.Name))
+	}
+
+	err := runs.Register(run)
+	if err != nil {
+		return fmt.Errorf("could not register kept containers: %w", err)
+	}
+
+	return runErr
+}
+
+// driver creates the orchestrator for the run, with a namespace unique to it,
+// so concurrent runs on the same host do not clean up each other's resources.
+func (c *Runner) driver(run *registry.Run) (orchestra.Driver, error) {
+	if c.Replay != "" {
+		// a replay creates no resources, but the run is still recorded with a namespace of its own
+		run.Namespace = "ci-" + run.ID
+
+		client, err := replay.NewReplayer(c.Replay)
+		if err != nil {
+			return nil, fmt.Errorf("could not create replay client: %w", err)
+		}
+
+		return client, nil
+	}
+
+	driverName, options, err := orchestra.ParseURL(c.Orchestrator, "ci")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse orchestrator: %w", err)
+	}
+
+	orchestrator, found := orchestra.Get(driverName)
+	if !found {
+		return nil, fmt.Errorf("could not get orchestrator (%q): %w", driverName, ErrOrchestratorNotFound)
+	}
+
+	options.Namespace = fmt.Sprintf("%s-%s", options.Namespace, run.ID)
+	options.Labels["orchestra.run"] = run.ID
+	options.Labels["orchestra.pipeline"] = run.Pipeline
+	run.Namespace = options.Namespace
+
+	client, err := orchestrator(options)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s client: %w", driverName, err)
+	}
+
+	if c.Record != "" {
+		return replay.NewRecorder(client, c.Record), nil
+	}
+
+	return client, nil
+}
+
 var (
 	ErrCouldNotBundle       = errors.New("could not bundle pipeline")
 	ErrOrchestratorNotFound = errors.New("orchestrator not found")
@@ -0,0 +1,71 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jtarchie/ci/commands"
+	"github.com/jtarchie/ci/registry"
+	. "github.com/onsi/gomega"
+)
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	pipelinePath := filepath.Join(dir, "pipeline.js")
+
+	err := os.WriteFile(pipelinePath, []byte(`
+const pipeline = () => {
+  run({ name: "failing", command: ["sh", "-c", "exit 1"] });
+};
+
+export { pipeline };
+`), 0o600)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	registryDir := t.TempDir()
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+
+	run := func(runner commands.Runner) {
+		pipeline, err := os.Open(pipelinePath)
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer pipeline.Close()
+
+		runner.Pipeline = pipeline
+		runner.Orchestrator = "native://" + t.TempDir()
+		runner.Registry = registryDir
+
+		assert.Expect(runner.Run()).To(Succeed())
+	}
+
+	// kept runs stay registered, and still write their recording
+	run(commands.Runner{KeepOnFailure: true})
+	run(commands.Runner{KeepOnFailure: true})
+	run(commands.Runner{KeepOnFailure: true, Record: fixture})
+	assert.Expect(fixture).To(BeAnExistingFile())
+
+	// a replay has no containers to keep, so it is not registered
+	run(commands.Runner{KeepOnFailure: true, Replay: fixture})
+
+	runs, err := registry.New(registryDir)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	all, err := runs.Runs()
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(all).To(HaveLen(3))
+
+	namespaces := map[string]bool{}
+
+	for _, run := range all {
+		assert.Expect(run.Namespace).To(Equal("ci-" + run.ID))
+		assert.Expect(run.Kept).To(HaveLen(1))
+
+		namespaces[run.Namespace] = true
+	}
+
+	assert.Expect(namespaces).To(HaveLen(3))
+}
@@ -7,4 +7,7 @@ const pipeline = () => {
   assert.containsString("Hello, World!", result.stdout);
 };
 
-export { pipeline };
+// images are pulled concurrently before the pipeline starts
+const images = ["alpine"];
+
+export { images, pipeline };
@@ -11,6 +11,8 @@ import (
 )
 
 type CLI struct {
+	GC     commands.GC     `cmd:"" help:"Remove resources left behind by runs that are no longer alive"`
+	Hijack commands.Hijack `cmd:"" help:"Open a shell in the kept container of a failed task"`
 	Runner commands.Runner `cmd:"" help:"Run a pipeline"`
 }
 
@@ -24,6 +24,7 @@ func TestExamples(t *testing.T) {
 
 	drivers := []string{
 		// "docker",
+		// the examples have images, which native ignores by default
 		"native",
 	}
 
@@ -37,12 +38,15 @@ func TestExamples(t *testing.T) {
 
 				assert := NewGomegaWithT(t)
 
-				session, err := gexec.Start(
-					exec.Command(
-						path, "runner",
-						"--orchestrator", driver,
-						examplePath,
-					), os.Stderr, os.Stderr)
+				command := exec.Command(
+					path, "runner",
+					"--orchestrator", driver,
+					examplePath,
+				)
+				// host paths are relative to the pipeline, not the working directory
+				command.Dir = t.TempDir()
+
+				session, err := gexec.Start(command, os.Stderr, os.Stderr)
 				assert.Expect(err).ToNot(HaveOccurred())
 				assert.Eventually(session, "5s").Should(gexec.Exit(0))
 			})
@@ -0,0 +1,349 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Filter decides whether a path, relative to the directory being archived, is included.
+// Returning false for a directory skips everything in it.
+type Filter func(path string, entry fs.DirEntry) bool
+
+// Tar writes the contents of dir to writer, with paths relative to dir.
+func Tar(dir string, writer io.Writer, filter Filter) error {
+	tarWriter := tar.NewWriter(writer)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		if relative == "." {
+			return nil
+		}
+
+		if filter != nil && !filter(relative, entry) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		return addEntry(tarWriter, path, filepath.ToSlash(relative), entry)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return nil
+}
+
+func addEntry(tarWriter *tar.Writer, path string, name string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+
+	link := ""
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err = os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("failed to read link: %w", err)
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("failed to create header: %w", err)
+	}
+
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	err = tarWriter.WriteHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open: %w", err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(tarWriter, file)
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+
+	return nil
+}
+
+var ErrInvalidPath = errors.New("path escapes the destination")
+
+// Untar extracts reader into dir, refusing entries that would be written outside of it.
+// That includes symlinks to outside of dir, and entries written through a symlink.
+func Untar(reader io.Reader, dir string) error {
+	tarReader := tar.NewReader(reader)
+	links := []string{}
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return checkLinks(dir, links)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		path, err := Join(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		err = checkParents(dir, path)
+		if err != nil {
+			return err
+		}
+
+		err = extractEntry(tarReader, header, dir, path)
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeSymlink {
+			links = append(links, path)
+		}
+	}
+}
+
+// checkParents returns an error when a directory between dir and path is a symlink,
+// as an entry written through it could end up outside of dir.
+func checkParents(dir string, path string) error {
+	dir = filepath.Clean(dir)
+
+	for parent := filepath.Dir(path); parent != dir && within(dir, parent); parent = filepath.Dir(parent) {
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to stat: %w", err)
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is a symlink", ErrInvalidPath, parent)
+		}
+	}
+
+	return nil
+}
+
+func removeSymlink(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return nil //nolint:nilerr
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove symlink: %w", err)
+	}
+
+	return nil
+}
+
+func extractEntry(tarReader *tar.Reader, header *tar.Header, dir string, path string) error {
+	mode := header.FileInfo().Mode()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		err := os.MkdirAll(path, mode.Perm()|0o700)
+		if err != nil {
+			return fmt.Errorf("failed to create dir: %w", err)
+		}
+	case tar.TypeReg:
+		err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("failed to create dir: %w", err)
+		}
+
+		// an existing symlink is replaced, rather than written through
+		err = removeSymlink(path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+
+		//nolint:gosec
+		_, err = io.Copy(file, tarReader)
+		if err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	case tar.TypeSymlink:
+		err := resolveLink(dir, filepath.Dir(path), header.Linkname)
+		if err != nil {
+			return fmt.Errorf("%s links to %s: %w", header.Name, header.Linkname, err)
+		}
+
+		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("failed to create dir: %w", err)
+		}
+
+		_ = os.Remove(path)
+
+		err = os.Symlink(header.Linkname, path)
+		if err != nil {
+			return fmt.Errorf("failed to create symlink: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// maxLinks is how many symlinks are followed when resolving a link, like the limit of the kernel.
+const maxLinks = 40
+
+// resolveLink returns an error when linkname, relative to base, leaves dir.
+// It is followed one name at a time through the symlinks already on disk, as the kernel would,
+// so a chain of links cannot escape where each of them on its own does not.
+func resolveLink(dir string, base string, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return ErrInvalidPath
+	}
+
+	current := base
+	pending := strings.Split(filepath.ToSlash(linkname), "/")
+	followed := 0
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, name)
+		}
+
+		if !within(dir, current) {
+			return ErrInvalidPath
+		}
+
+		info, err := os.Lstat(current)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		followed++
+		if followed > maxLinks {
+			return fmt.Errorf("%w: too many links", ErrInvalidPath)
+		}
+
+		target, err := os.Readlink(current)
+		if err != nil {
+			return fmt.Errorf("failed to read link: %w", err)
+		}
+
+		if filepath.IsAbs(target) {
+			return ErrInvalidPath
+		}
+
+		current = filepath.Dir(current)
+		pending = append(strings.Split(filepath.ToSlash(target), "/"), pending...)
+	}
+
+	return nil
+}
+
+// checkLinks resolves the extracted links again, once they have all been extracted,
+// as a later entry can change what an earlier link resolves through.
+// A link that now leaves dir is removed.
+func checkLinks(dir string, links []string) error {
+	for _, path := range links {
+		linkname, err := os.Readlink(path)
+		if err != nil {
+			// it was replaced by a later entry
+			continue
+		}
+
+		err = resolveLink(dir, filepath.Dir(path), linkname)
+		if err != nil {
+			_ = os.Remove(path)
+
+			return fmt.Errorf("%s links to %s: %w", path, linkname, err)
+		}
+	}
+
+	return nil
+}
+
+// Join returns path within dir, or an error when it would escape dir.
+func Join(dir string, path string) (string, error) {
+	joined := filepath.Join(dir, filepath.FromSlash(path))
+
+	if !within(dir, joined) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	}
+
+	return joined, nil
+}
+
+// within is true when the cleaned path is dir, or in it.
+func within(dir string, path string) bool {
+	dir = filepath.Clean(dir)
+
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// Copy copies the contents of source into destination, as if it was archived and extracted.
+func Copy(source string, destination string, filter Filter) error {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(Tar(source, writer, filter))
+	}()
+	defer reader.Close()
+
+	err := os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	return Untar(reader, destination)
+}
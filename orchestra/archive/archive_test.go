@@ -0,0 +1,192 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra/archive"
+	. "github.com/onsi/gomega"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	source := t.TempDir()
+
+	for name, contents := range map[string]string{
+		".gitignore":        "*.log\n/build/\n!keep.log\n",
+		".git/HEAD":         "ref: refs/heads/main",
+		"main.go":           "package main",
+		"debug.log":         "ignored",
+		"keep.log":          "kept",
+		"build/binary":      "ignored",
+		"nested/build/file": "kept",
+		"nested/.gitignore": "secret",
+		"nested/secret":     "ignored",
+	} {
+		path := filepath.Join(source, name)
+		assert.Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+		assert.Expect(os.WriteFile(path, []byte(contents), 0o600)).To(Succeed())
+	}
+
+	destination := t.TempDir()
+
+	err := archive.Copy(source, destination, archive.GitIgnore(source))
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	for _, name := range []string{"main.go", "keep.log", "nested/build/file"} {
+		assert.Expect(filepath.Join(destination, name)).To(BeAnExistingFile(), name)
+	}
+
+	for _, name := range []string{".git", "debug.log", "build", "nested/secret"} {
+		assert.Expect(filepath.Join(destination, name)).NotTo(BeAnExistingFile(), name)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	_, err := archive.Join(t.TempDir(), "../escape")
+	assert.Expect(err).To(MatchError(archive.ErrInvalidPath))
+}
+
+func TestUntar(t *testing.T) {
+	t.Parallel()
+
+	tarball := func(assert *WithT, headers ...*tar.Header) *bytes.Buffer {
+		buffer := &bytes.Buffer{}
+		writer := tar.NewWriter(buffer)
+
+		for _, header := range headers {
+			if header.Typeflag == tar.TypeReg {
+				header.Size = int64(len(header.Name))
+			}
+
+			assert.Expect(writer.WriteHeader(header)).To(Succeed())
+
+			if header.Typeflag == tar.TypeReg {
+				_, err := writer.Write([]byte(header.Name))
+				assert.Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		assert.Expect(writer.Close()).To(Succeed())
+
+		return buffer
+	}
+
+	t.Run("rejects symlinks out of the destination", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		outside := t.TempDir()
+
+		for _, link := range []string{outside, "../outside", "nested/../../outside"} {
+			err := archive.Untar(tarball(assert,
+				&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: link},
+				&tar.Header{Typeflag: tar.TypeReg, Name: "link/escaped", Mode: 0o600},
+			), t.TempDir())
+			assert.Expect(err).To(MatchError(archive.ErrInvalidPath), link)
+		}
+
+		assert.Expect(filepath.Join(outside, "escaped")).NotTo(BeAnExistingFile())
+	})
+
+	t.Run("rejects chains of symlinks out of the destination", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		chains := map[string][]*tar.Header{
+			// each link stays in the destination by its text, but not through the other
+			"through an extracted link": {
+				{Typeflag: tar.TypeSymlink, Name: "s2", Linkname: "."},
+				{Typeflag: tar.TypeSymlink, Name: "evil", Linkname: "s2/.."},
+			},
+			"through a link extracted later": {
+				{Typeflag: tar.TypeSymlink, Name: "evil", Linkname: "s2/.."},
+				{Typeflag: tar.TypeSymlink, Name: "s2", Linkname: "."},
+			},
+			"through a link replacing a directory": {
+				{Typeflag: tar.TypeDir, Name: "s2", Mode: 0o700},
+				{Typeflag: tar.TypeSymlink, Name: "evil", Linkname: "s2/.."},
+				{Typeflag: tar.TypeSymlink, Name: "s2", Linkname: "nested/.."},
+				{Typeflag: tar.TypeDir, Name: "nested", Mode: 0o700},
+			},
+		}
+
+		for name, headers := range chains {
+			destination := filepath.Join(t.TempDir(), "destination")
+			assert.Expect(os.Mkdir(destination, 0o700)).To(Succeed())
+
+			err := archive.Untar(tarball(assert, headers...), destination)
+			assert.Expect(err).To(MatchError(archive.ErrInvalidPath), name)
+
+			// nothing in the destination reaches its parent
+			entries, err := os.ReadDir(filepath.Join(destination, "evil"))
+			if err == nil {
+				for _, entry := range entries {
+					assert.Expect(entry.Name()).NotTo(Equal("destination"), name)
+				}
+			}
+		}
+	})
+
+	t.Run("does not write through existing symlinks", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		outside := t.TempDir()
+		destination := t.TempDir()
+
+		assert.Expect(os.Symlink(outside, filepath.Join(destination, "link"))).To(Succeed())
+
+		err := archive.Untar(tarball(assert,
+			&tar.Header{Typeflag: tar.TypeReg, Name: "link/escaped", Mode: 0o600},
+		), destination)
+		assert.Expect(err).To(MatchError(archive.ErrInvalidPath))
+		assert.Expect(filepath.Join(outside, "escaped")).NotTo(BeAnExistingFile())
+
+		// a file replaces a symlink, rather than writing to its target
+		target := filepath.Join(outside, "target")
+		assert.Expect(os.WriteFile(target, []byte("unchanged"), 0o600)).To(Succeed())
+		assert.Expect(os.Symlink(target, filepath.Join(destination, "file"))).To(Succeed())
+
+		err = archive.Untar(tarball(assert,
+			&tar.Header{Typeflag: tar.TypeReg, Name: "file", Mode: 0o600},
+		), destination)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		contents, err := os.ReadFile(target)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(string(contents)).To(Equal("unchanged"))
+	})
+
+	t.Run("extracts symlinks within the destination", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		destination := t.TempDir()
+
+		err := archive.Untar(tarball(assert,
+			&tar.Header{Typeflag: tar.TypeReg, Name: "nested/file", Mode: 0o600},
+			&tar.Header{Typeflag: tar.TypeSymlink, Name: "nested/link", Linkname: "file"},
+			&tar.Header{Typeflag: tar.TypeSymlink, Name: "up", Linkname: "nested/../nested/file"},
+		), destination)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		contents, err := os.ReadFile(filepath.Join(destination, "up"))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(string(contents)).To(Equal("nested/file"))
+	})
+}
@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type ignorePattern struct {
+	anchored bool
+	base     string
+	dirOnly  bool
+	matcher  *regexp.Regexp
+	negate   bool
+}
+
+// GitIgnore returns a filter that skips the `.git` directory and anything matched by
+// the `.gitignore` files in dir and its subdirectories.
+// It covers the common syntax, not every corner of git's implementation.
+func GitIgnore(dir string) Filter {
+	patterns := readIgnoreFile(dir, "")
+
+	return func(relative string, entry fs.DirEntry) bool {
+		relative = filepath.ToSlash(relative)
+
+		if entry.IsDir() && path.Base(relative) == ".git" {
+			return false
+		}
+
+		ignored := false
+
+		for _, pattern := range patterns {
+			if pattern.matches(relative, entry.IsDir()) {
+				ignored = !pattern.negate
+			}
+		}
+
+		if ignored {
+			return false
+		}
+
+		// directories are walked before their contents, so their patterns are in place in time
+		if entry.IsDir() {
+			patterns = append(patterns, readIgnoreFile(dir, relative)...)
+		}
+
+		return true
+	}
+}
+
+func readIgnoreFile(dir string, base string) []ignorePattern {
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(base), ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	patterns := []ignorePattern{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := ignorePattern{base: base}
+
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// a slash anywhere but the end anchors the pattern to the directory of the .gitignore
+		if strings.Contains(line, "/") {
+			pattern.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		matcher, err := regexp.Compile(globToRegexp(line))
+		if err != nil {
+			continue
+		}
+
+		pattern.matcher = matcher
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+func (p ignorePattern) matches(relative string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(relative, p.base+"/") {
+			return false
+		}
+
+		relative = strings.TrimPrefix(relative, p.base+"/")
+	}
+
+	if p.anchored {
+		return p.matcher.MatchString(relative)
+	}
+
+	return p.matcher.MatchString(path.Base(relative))
+}
+
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+
+	builder.WriteString("^")
+
+	for index := 0; index < len(glob); index++ {
+		char := glob[index]
+
+		switch {
+		case strings.HasPrefix(glob[index:], "**/"):
+			builder.WriteString("(.*/)?")
+
+			index += 2
+		case strings.HasPrefix(glob[index:], "/**"):
+			builder.WriteString("(/.*)?")
+
+			index += 2
+		case strings.HasPrefix(glob[index:], "**"):
+			builder.WriteString(".*")
+
+			index++
+		case char == '*':
+			builder.WriteString("[^/]*")
+		case char == '?':
+			builder.WriteString("[^/]")
+		case char == '[':
+			end := strings.IndexByte(glob[index:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+
+				continue
+			}
+
+			class := glob[index+1 : index+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			builder.WriteString("[" + class + "]")
+
+			index += end
+		case char == '\\' && index+1 < len(glob):
+			index++
+			builder.WriteString(regexp.QuoteMeta(string(glob[index])))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	builder.WriteString("$")
+
+	return builder.String()
+}
@@ -0,0 +1,23 @@
+package orchestra
+
+import (
+	"context"
+	"io"
+)
+
+// Build is an image to build from a Dockerfile.
+type Build struct {
+	Args map[string]string
+	// Context is the build context as a tar stream.
+	Context io.Reader
+	// Dockerfile is the path of the Dockerfile in the context, `Dockerfile` when empty.
+	Dockerfile string
+	// Tag is the reference of the image, when empty the image is only known by its ID.
+	Tag string
+}
+
+// ImageBuilder is implemented by drivers that can build images for later tasks to run.
+type ImageBuilder interface {
+	// BuildImage returns the reference later tasks can use as their image.
+	BuildImage(ctx context.Context, build Build) (string, error)
+}
@@ -0,0 +1,104 @@
+package orchestra
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Capabilities describes which features of a task a driver is able to honor.
+type Capabilities struct {
+	// IgnoreImages runs tasks with an image without it, when a driver without images was told to.
+	IgnoreImages bool
+	Images       bool
+	// ImagePrefixes limits images to those with one of the prefixes, when it is not empty,
+	// such as a driver that only has extracted root filesystems.
+	ImagePrefixes []string
+	// Networking lets the ports of services be reached from other tasks.
+	Networking       bool
+	Privileged       bool
+	ResourceLimits   bool
+	Services         bool
+	Stdin            bool
+	TTY              bool
+	Volumes          bool
+	VolumeSizeLimits bool
+}
+
+var (
+	ErrInvalidMount          = errors.New("invalid mount")
+	ErrUnsupportedCapability = errors.New("unsupported by driver")
+)
+
+// SupportsImage is true when the driver runs tasks in the image, rather than ignoring it.
+func (c Capabilities) SupportsImage(image string) bool {
+	if !c.Images {
+		return false
+	}
+
+	if len(c.ImagePrefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range c.ImagePrefixes {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate returns an error when the task requires a feature the driver does not support.
+func (c Capabilities) Validate(task Task) error {
+	if task.Image != "" && !c.SupportsImage(task.Image) && !c.IgnoreImages {
+		if c.Images {
+			return fmt.Errorf("images other than %s are %w", strings.Join(c.ImagePrefixes, ", "), ErrUnsupportedCapability)
+		}
+
+		return fmt.Errorf("images are %w", ErrUnsupportedCapability)
+	}
+
+	if len(task.Mounts) > 0 && !c.Volumes {
+		return fmt.Errorf("volumes are %w", ErrUnsupportedCapability)
+	}
+
+	for _, mount := range task.Mounts {
+		switch mount.Kind() {
+		case MountTypeVolume, MountTypeTmpfs:
+		case MountTypeHost:
+			if mount.HostPath == "" {
+				return fmt.Errorf("%w: %s has no host path", ErrInvalidMount, mount.Name)
+			}
+		default:
+			return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidMount, mount.Name, mount.Type)
+		}
+
+		if mount.Size > 0 && !c.VolumeSizeLimits {
+			return fmt.Errorf("volume sizes are %w", ErrUnsupportedCapability)
+		}
+	}
+
+	if task.Stdin != nil && !c.Stdin {
+		return fmt.Errorf("stdin is %w", ErrUnsupportedCapability)
+	}
+
+	if !task.Resources.IsZero() && !c.ResourceLimits {
+		return fmt.Errorf("resource limits are %w", ErrUnsupportedCapability)
+	}
+
+	return nil
+}
+
+// ValidateService returns an error when the driver cannot run the service, or its task.
+func (c Capabilities) ValidateService(service Service) error {
+	if !c.Services {
+		return fmt.Errorf("services are %w", ErrUnsupportedCapability)
+	}
+
+	if len(service.Ports) > 0 && !c.Networking {
+		return fmt.Errorf("service ports need networking, which is %w", ErrUnsupportedCapability)
+	}
+
+	return c.Validate(service.Task)
+}
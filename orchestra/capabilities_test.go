@@ -0,0 +1,117 @@
+package orchestra_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	all := orchestra.Capabilities{
+		Images:           true,
+		Networking:       true,
+		Privileged:       true,
+		ResourceLimits:   true,
+		Services:         true,
+		Stdin:            true,
+		TTY:              true,
+		Volumes:          true,
+		VolumeSizeLimits: true,
+	}
+
+	tasks := map[string]orchestra.Task{
+		"image":     {Image: "alpine"},
+		"volume":    {Mounts: orchestra.Mounts{{Name: "data", Path: "/data"}}},
+		"size":      {Mounts: orchestra.Mounts{{Name: "data", Path: "/data", Size: 1024}}},
+		"stdin":     {Stdin: strings.NewReader("input")},
+		"resources": {Resources: orchestra.Resources{Memory: 1024}},
+	}
+
+	t.Run("accepts everything the driver supports", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		for _, task := range tasks {
+			assert.Expect(all.Validate(task)).To(Succeed())
+		}
+	})
+
+	t.Run("rejects what the driver does not support", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		without := map[string]orchestra.Capabilities{
+			"image":     {},
+			"volume":    {Images: true},
+			"size":      {Volumes: true},
+			"stdin":     {},
+			"resources": {},
+		}
+
+		for name, task := range tasks {
+			err := without[name].Validate(task)
+			assert.Expect(err).To(MatchError(orchestra.ErrUnsupportedCapability), name)
+		}
+	})
+
+	t.Run("ignores images when told to", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		capabilities := orchestra.Capabilities{IgnoreImages: true}
+		assert.Expect(capabilities.Validate(tasks["image"])).To(Succeed())
+	})
+
+	t.Run("only accepts images with the prefixes of the driver", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		capabilities := orchestra.Capabilities{Images: true, ImagePrefixes: []string{"rootfs:"}}
+		assert.Expect(capabilities.SupportsImage("rootfs:image")).To(BeTrue())
+		assert.Expect(capabilities.Validate(orchestra.Task{Image: "rootfs:image"})).To(Succeed())
+
+		assert.Expect(capabilities.SupportsImage("alpine")).To(BeFalse())
+		assert.Expect(capabilities.Validate(tasks["image"])).To(MatchError(orchestra.ErrUnsupportedCapability))
+
+		capabilities.IgnoreImages = true
+		assert.Expect(capabilities.Validate(tasks["image"])).To(Succeed())
+	})
+
+	t.Run("rejects invalid mounts", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		err := all.Validate(orchestra.Task{Mounts: orchestra.Mounts{{Name: "input", Type: orchestra.MountTypeHost}}})
+		assert.Expect(err).To(MatchError(orchestra.ErrInvalidMount))
+
+		err = all.Validate(orchestra.Task{Mounts: orchestra.Mounts{{Name: "input", Type: "nfs"}}})
+		assert.Expect(err).To(MatchError(orchestra.ErrInvalidMount))
+	})
+
+	t.Run("services need networking for their ports", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		service := orchestra.Service{Name: "database", Ports: []int{5432}}
+		assert.Expect(all.ValidateService(service)).To(Succeed())
+
+		err := orchestra.Capabilities{Services: true}.ValidateService(service)
+		assert.Expect(err).To(MatchError(orchestra.ErrUnsupportedCapability))
+
+		err = orchestra.Capabilities{Networking: true}.ValidateService(service)
+		assert.Expect(err).To(MatchError(orchestra.ErrUnsupportedCapability))
+
+		service.Ports = nil
+		assert.Expect(orchestra.Capabilities{Services: true}.ValidateService(service)).To(Succeed())
+	})
+}
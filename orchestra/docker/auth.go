@@ -0,0 +1,192 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// dockerHubServer is the key docker uses for Docker Hub in its config file.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+type configAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// dockerConfig is the part of the docker CLI config file with credentials.
+type dockerConfig struct {
+	Auths       map[string]configAuth `json:"auths"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+	CredsStore  string                `json:"credsStore"`
+}
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// registryAuth returns the encoded credentials to pull an image with.
+// The credentials of the task are used first, then the docker CLI config file, including its credential helpers.
+// An empty string pulls anonymously.
+func registryAuth(name string, auth *orchestra.RegistryAuth) (string, error) {
+	server, err := registryServer(name)
+	if err != nil {
+		return "", err
+	}
+
+	var config *registry.AuthConfig
+
+	if auth != nil {
+		config = &registry.AuthConfig{
+			Username: auth.Username,
+			Password: auth.Password,
+		}
+	} else {
+		config, err = configCredentials(server)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if config == nil {
+		return "", nil
+	}
+
+	config.ServerAddress = server
+
+	encoded, err := registry.EncodeAuthConfig(*config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// registryServer is the server of an image, as it is named in the docker config file.
+func registryServer(name string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image %s: %w", name, err)
+	}
+
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		return dockerHubServer, nil
+	}
+
+	return domain, nil
+}
+
+func configPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// configCredentials finds the credentials of server in the docker config file, nil when there are none.
+func configCredentials(server string) (*registry.AuthConfig, error) {
+	contents, err := os.ReadFile(configPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config: %w", err)
+	}
+
+	var config dockerConfig
+
+	err = json.Unmarshal(contents, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+
+	if helper, ok := config.CredHelpers[server]; ok {
+		return helperCredentials(helper, server)
+	}
+
+	if config.CredsStore != "" {
+		return helperCredentials(config.CredsStore, server)
+	}
+
+	for _, key := range []string{server, "https://" + server, "http://" + server} {
+		auth, ok := config.Auths[key]
+		if !ok {
+			continue
+		}
+
+		credentials := &registry.AuthConfig{
+			IdentityToken: auth.IdentityToken,
+		}
+
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidCredentials, key, err)
+			}
+
+			credentials.Username, credentials.Password, _ = strings.Cut(string(decoded), ":")
+		}
+
+		return credentials, nil
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+// helperCredentials runs a docker credential helper, following its protocol:
+// https://github.com/docker/docker-credential-helpers
+func helperCredentials(helper string, server string) (*registry.AuthConfig, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	//nolint:gosec
+	command := exec.Command("docker-credential-"+helper, "get")
+	command.Stdin = strings.NewReader(server)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	err := command.Run()
+	if err != nil {
+		// helpers report missing credentials on stdout, that is an anonymous pull, not an error
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return nil, nil //nolint:nilnil
+		}
+
+		return nil, fmt.Errorf("failed to run credential helper %s: %w: %s", helper, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output struct {
+		Secret   string `json:"Secret"`
+		Username string `json:"Username"`
+	}
+
+	err = json.Unmarshal(stdout.Bytes(), &output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credential helper %s: %w", helper, err)
+	}
+
+	// a username of <token> means the secret is an identity token
+	if output.Username == "<token>" {
+		return &registry.AuthConfig{IdentityToken: output.Secret}, nil
+	}
+
+	return &registry.AuthConfig{
+		Username: output.Username,
+		Password: output.Secret,
+	}, nil
+}
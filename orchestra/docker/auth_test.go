@@ -0,0 +1,162 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+// fakeHelper is a docker credential helper with credentials for two registries.
+const fakeHelper = `#!/bin/sh
+read -r server
+case "$server" in
+  registry.example.com) echo '{"ServerURL":"registry.example.com","Username":"helper","Secret":"helper-password"}' ;;
+  token.example.com) echo '{"ServerURL":"token.example.com","Username":"<token>","Secret":"identity"}' ;;
+  *) echo "credentials not found in native keychain"; exit 1 ;;
+esac
+`
+
+// The environment is changed, so these cannot run in parallel.
+//
+//nolint:paralleltest
+func TestConfigCredentials(t *testing.T) {
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, "docker-credential-fake"), []byte(fakeHelper), 0o700) //nolint:gosec
+	NewGomegaWithT(t).Expect(err).NotTo(HaveOccurred())
+
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	writeConfig := func(assert *WithT, config map[string]any) {
+		dir := t.TempDir()
+
+		contents, err := json.Marshal(config)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(os.WriteFile(filepath.Join(dir, "config.json"), contents, 0o600)).To(Succeed())
+
+		t.Setenv("DOCKER_CONFIG", dir)
+	}
+
+	t.Run("no config file", func(t *testing.T) {
+		assert := NewGomegaWithT(t)
+
+		t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+		credentials, err := configCredentials("registry.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(BeNil())
+	})
+
+	t.Run("auths", func(t *testing.T) {
+		assert := NewGomegaWithT(t)
+
+		writeConfig(assert, map[string]any{
+			"auths": map[string]any{
+				"registry.example.com":      map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte("user:pass:word"))},
+				"https://other.example.com": map[string]string{"identitytoken": "identity"},
+				"invalid.example.com":       map[string]string{"auth": "not base64!"},
+			},
+		})
+
+		credentials, err := configCredentials("registry.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(Equal(&registry.AuthConfig{Username: "user", Password: "pass:word"}))
+
+		credentials, err = configCredentials("other.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(Equal(&registry.AuthConfig{IdentityToken: "identity"}))
+
+		credentials, err = configCredentials("missing.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(BeNil())
+
+		_, err = configCredentials("invalid.example.com")
+		assert.Expect(err).To(MatchError(ErrInvalidCredentials))
+	})
+
+	t.Run("credential helpers", func(t *testing.T) {
+		assert := NewGomegaWithT(t)
+
+		writeConfig(assert, map[string]any{
+			"credHelpers": map[string]string{
+				"registry.example.com": "fake",
+				"token.example.com":    "fake",
+				"missing.example.com":  "fake",
+				"broken.example.com":   "does-not-exist",
+			},
+		})
+
+		credentials, err := configCredentials("registry.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(Equal(&registry.AuthConfig{Username: "helper", Password: "helper-password"}))
+
+		credentials, err = configCredentials("token.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(Equal(&registry.AuthConfig{IdentityToken: "identity"}))
+
+		credentials, err = configCredentials("missing.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(BeNil())
+
+		_, err = configCredentials("broken.example.com")
+		assert.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("credentials store", func(t *testing.T) {
+		assert := NewGomegaWithT(t)
+
+		writeConfig(assert, map[string]any{"credsStore": "fake"})
+
+		credentials, err := configCredentials("registry.example.com")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(credentials).To(Equal(&registry.AuthConfig{Username: "helper", Password: "helper-password"}))
+	})
+
+	t.Run("the credentials of the task come first", func(t *testing.T) {
+		assert := NewGomegaWithT(t)
+
+		writeConfig(assert, map[string]any{"credsStore": "fake"})
+
+		encoded, err := registryAuth("registry.example.com/image:latest", &orchestra.RegistryAuth{Username: "task", Password: "secret"})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		decoded, err := registry.DecodeAuthConfig(encoded)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(decoded.Username).To(Equal("task"))
+		assert.Expect(decoded.Password).To(Equal("secret"))
+		assert.Expect(decoded.ServerAddress).To(Equal("registry.example.com"))
+
+		encoded, err = registryAuth("registry.example.com/image:latest", nil)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		decoded, err = registry.DecodeAuthConfig(encoded)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(decoded.Username).To(Equal("helper"))
+
+		encoded, err = registryAuth("busybox", nil)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(encoded).To(BeEmpty())
+	})
+}
+
+func TestRegistryServer(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	for name, expected := range map[string]string{
+		"busybox":                           dockerHubServer,
+		"library/busybox:latest":            dockerHubServer,
+		"registry.example.com:5000/image:1": "registry.example.com:5000",
+		"ghcr.io/owner/image":               "ghcr.io",
+	} {
+		server, err := registryServer(name)
+		assert.Expect(err).NotTo(HaveOccurred(), name)
+		assert.Expect(server).To(Equal(expected), name)
+	}
+}
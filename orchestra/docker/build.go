@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+var ErrImageNotBuilt = errors.New("image was not built")
+
+// BuildImage implements orchestra.ImageBuilder.
+func (d *Docker) BuildImage(ctx context.Context, build orchestra.Build) (string, error) {
+	args := map[string]*string{}
+	for name, value := range build.Args {
+		args[name] = &value
+	}
+
+	options := types.ImageBuildOptions{
+		BuildArgs:   args,
+		Dockerfile:  build.Dockerfile,
+		ForceRemove: true,
+		Labels:      d.resourceLabels(),
+		Remove:      true,
+	}
+
+	if build.Tag != "" {
+		options.Tags = []string{build.Tag}
+	}
+
+	response, err := d.client.ImageBuild(ctx, build.Context, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+	defer response.Body.Close()
+
+	id, err := buildOutput(response.Body, d.logger.With("tag", build.Tag))
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+
+	if build.Tag != "" {
+		return build.Tag, nil
+	}
+
+	return id, nil
+}
+
+// buildOutput logs the output of a build and returns the ID of the image.
+func buildOutput(reader io.Reader, logger *slog.Logger) (string, error) {
+	decoder := json.NewDecoder(reader)
+	id := ""
+
+	for {
+		var message jsonmessage.JSONMessage
+
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to decode output: %w", err)
+		}
+
+		if message.Error != nil {
+			return "", message.Error
+		}
+
+		if line := strings.TrimSpace(message.Stream); line != "" {
+			logger.Info("image.build", "output", line)
+		}
+
+		if message.Aux != nil {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+
+			if json.Unmarshal(*message.Aux, &aux) == nil && aux.ID != "" {
+				id = aux.ID
+			}
+		}
+	}
+
+	if id == "" {
+		return "", ErrImageNotBuilt
+	}
+
+	return id, nil
+}
@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBuildOutput(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("returns the ID of the image", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		id, err := buildOutput(strings.NewReader(
+			`{"stream":"Step 1/2 : FROM busybox\n"}`+"\n"+
+				`{"stream":"Successfully built abc123\n"}`+"\n"+
+				`{"aux":{"ID":"sha256:abc123"}}`,
+		), logger)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(id).To(Equal("sha256:abc123"))
+	})
+
+	t.Run("an error from the build", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := buildOutput(strings.NewReader(
+			`{"stream":"Step 1/2 : RUN false\n"}`+"\n"+
+				`{"errorDetail":{"code":1,"message":"returned a non-zero code: 1"},"error":"returned a non-zero code: 1"}`,
+		), logger)
+		assert.Expect(err).To(MatchError(ContainSubstring("non-zero code")))
+	})
+
+	t.Run("no image was built", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := buildOutput(strings.NewReader(`{"stream":"Step 1/2 : FROM busybox\n"}`), logger)
+		assert.Expect(err).To(MatchError(ErrImageNotBuilt))
+	})
+}
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// Resources implements orchestra.Collector.
+func (d *Docker) Resources(ctx context.Context) ([]orchestra.Resource, error) {
+	filter := filters.NewArgs(filters.Arg("label", "orchestra.namespace"))
+
+	containers, err := d.client.ContainerList(ctx, container.ListOptions{All: true, Filters: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	resources := []orchestra.Resource{}
+
+	for _, container := range containers {
+		resources = append(resources, orchestra.Resource{
+			CreatedAt: time.Unix(container.Created, 0),
+			ID:        strings.TrimPrefix(container.Names[0], "/"),
+			Kind:      "container",
+			Labels:    container.Labels,
+			Namespace: container.Labels["orchestra.namespace"],
+			Owner:     parseOwner(container.Labels),
+		})
+	}
+
+	volumes, err := d.client.VolumeList(ctx, volume.ListOptions{Filters: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	for _, volume := range volumes.Volumes {
+		createdAt, _ := time.Parse(time.RFC3339, volume.CreatedAt)
+
+		resources = append(resources, orchestra.Resource{
+			CreatedAt: createdAt,
+			ID:        volume.Name,
+			Kind:      "volume",
+			Labels:    volume.Labels,
+			Namespace: volume.Labels["orchestra.namespace"],
+			Owner:     parseOwner(volume.Labels),
+		})
+	}
+
+	networks, err := d.client.NetworkList(ctx, network.ListOptions{Filters: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	for _, network := range networks {
+		resources = append(resources, orchestra.Resource{
+			CreatedAt: network.Created,
+			ID:        network.Name,
+			Kind:      "network",
+			Labels:    network.Labels,
+			Namespace: network.Labels["orchestra.namespace"],
+			Owner:     parseOwner(network.Labels),
+		})
+	}
+
+	return resources, nil
+}
+
+// RemoveResource implements orchestra.Collector.
+func (d *Docker) RemoveResource(ctx context.Context, resource orchestra.Resource) error {
+	switch resource.Kind {
+	case "container":
+		err := d.client.ContainerRemove(ctx, resource.ID, container.RemoveOptions{Force: true})
+		if err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", resource.ID, err)
+		}
+	case "network":
+		err := d.client.NetworkRemove(ctx, resource.ID)
+		if err != nil {
+			return fmt.Errorf("failed to remove network %s: %w", resource.ID, err)
+		}
+	case "volume":
+		err := d.client.VolumeRemove(ctx, resource.ID, true)
+		if err != nil {
+			return fmt.Errorf("failed to remove volume %s: %w", resource.ID, err)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownResource, resource.Kind)
+	}
+
+	return nil
+}
+
+// parseOwner returns an empty owner for resources without a valid label,
+// which is never alive, so they can still be collected.
+func parseOwner(labels map[string]string) orchestra.Owner {
+	owner, _ := orchestra.ParseOwner(labels["orchestra.owner"])
+
+	return owner
+}
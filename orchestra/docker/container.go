@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
@@ -19,13 +21,21 @@ import (
 type DockerContainer struct {
 	id     string
 	client *client.Client
-	task   orchestra.Task
+	// startedAt is the local time the container was started, or reattached to,
+	// as the clock of a remote daemon can differ from this one.
+	startedAt time.Time
+	task      orchestra.Task
+	timedOut  atomic.Bool
+	usage     *usageSampler
 }
 
 type DockerContainerStatus struct {
-	state *types.ContainerState
+	state    *types.ContainerState
+	timedOut bool
 }
 
+// Status implements orchestra.Container.
+// Docker has no timeout for containers, so it is enforced when the status is checked.
 func (d *DockerContainer) Status(ctx context.Context) (orchestra.ContainerStatus, error) {
 	// doc: https://docs.docker.com/reference/api/engine/version/v1.43/#tag/Container/operation/ContainerInspect
 	inspection, err := d.client.ContainerInspect(ctx, d.id)
@@ -33,9 +43,37 @@ func (d *DockerContainer) Status(ctx context.Context) (orchestra.ContainerStatus
 		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
-	return &DockerContainerStatus{
+	status := &DockerContainerStatus{
 		state: inspection.State,
-	}, nil
+	}
+
+	if d.task.Timeout > 0 && status.State() == orchestra.StateRunning &&
+		time.Since(d.startedAt) > d.task.Timeout {
+		err = d.client.ContainerKill(ctx, d.id, "KILL")
+		if err != nil {
+			return nil, fmt.Errorf("failed to kill container after timeout: %w", err)
+		}
+
+		d.timedOut.Store(true)
+	}
+
+	status.timedOut = d.timedOut.Load()
+
+	return status, nil
+}
+
+// Exec implements orchestra.Container.
+func (d *DockerContainer) Exec(
+	ctx context.Context,
+	command []string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+) (int, error) {
+	return execCommand(ctx, d.client, d.id, command, stdin, stdout, stderr)
+}
+
+func (d *DockerContainer) ID() string {
+	return d.id
 }
 
 func (d *DockerContainer) Logs(ctx context.Context, stdout, stderr io.Writer) error {
@@ -58,6 +96,8 @@ func (d *DockerContainer) Logs(ctx context.Context, stdout, stderr io.Writer) er
 }
 
 func (d *DockerContainer) Cleanup(ctx context.Context) error {
+	d.usage.cancel()
+
 	err := d.client.ContainerRemove(ctx, d.id, container.RemoveOptions{
 		Force:         true,
 		RemoveLinks:   false,
@@ -71,56 +111,215 @@ func (d *DockerContainer) Cleanup(ctx context.Context) error {
 }
 
 func (s *DockerContainerStatus) IsDone() bool {
-	return s.state.Status == "exited"
+	return s.State().IsDone()
+}
+
+// State implements orchestra.ContainerStatus.
+// A container that is dead, or failed to start, is errored rather than left pending.
+func (s *DockerContainerStatus) State() orchestra.State {
+	if s.timedOut {
+		return orchestra.StateTimedOut
+	}
+
+	switch s.state.Status {
+	case "created":
+		if s.state.Error != "" {
+			return orchestra.StateErrored
+		}
+
+		return orchestra.StatePending
+	case "exited":
+		if s.state.Error != "" {
+			return orchestra.StateErrored
+		}
+
+		return orchestra.ExitState(s.state.ExitCode, s.state.OOMKilled)
+	case "dead":
+		return orchestra.StateErrored
+	default:
+		return orchestra.StateRunning
+	}
+}
+
+// Reason implements orchestra.ContainerStatus.
+func (s *DockerContainerStatus) Reason() string {
+	switch state := s.State(); state {
+	case orchestra.StateTimedOut:
+		return "killed after timeout"
+	case orchestra.StateOOMKilled:
+		return "killed for going over its memory limit"
+	case orchestra.StateFailed:
+		return fmt.Sprintf("exited with code %d", s.state.ExitCode)
+	case orchestra.StateErrored:
+		if s.state.Error != "" {
+			return s.state.Error
+		}
+
+		return "container is " + s.state.Status
+	case orchestra.StatePending, orchestra.StateRunning, orchestra.StateSucceeded:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// StartedAt implements orchestra.ContainerStatus.
+func (s *DockerContainerStatus) StartedAt() time.Time {
+	return parseTime(s.state.StartedAt)
+}
+
+// FinishedAt implements orchestra.ContainerStatus.
+func (s *DockerContainerStatus) FinishedAt() time.Time {
+	return parseTime(s.state.FinishedAt)
+}
+
+// parseTime returns the zero time for the times docker has not set,
+// which it reports as "0001-01-01T00:00:00Z".
+func parseTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || parsed.Year() <= 1 {
+		return time.Time{}
+	}
+
+	return parsed
+}
+
+func (s *DockerContainerStatus) IsReady() bool {
+	if !s.state.Running {
+		return false
+	}
+
+	return s.state.Health == nil || s.state.Health.Status == types.Healthy
+}
+
+func (s *DockerContainerStatus) IsOOMKilled() bool {
+	return s.state.OOMKilled
 }
 
 func (s *DockerContainerStatus) ExitCode() int {
 	return s.state.ExitCode
 }
 
-func (d *Docker) RunContainer(ctx context.Context, task orchestra.Task) (orchestra.Container, error) {
-	reader, err := d.client.ImagePull(ctx, task.Image, image.PullOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to initiate pull image: %w", err)
+func resources(limits orchestra.Resources) container.Resources {
+	resources := container.Resources{
+		Memory:   limits.Memory,
+		NanoCPUs: int64(limits.CPU * 1e9),
 	}
 
-	_, err = io.Copy(io.Discard, reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to pull image: %w", err)
+	if limits.Pids > 0 {
+		resources.PidsLimit = &limits.Pids
 	}
 
-	containerName := fmt.Sprintf("%s-%s", d.namespace, task.ID)
+	return resources
+}
 
+func (d *Docker) mounts(ctx context.Context, taskMounts orchestra.Mounts) ([]mount.Mount, error) {
 	mounts := []mount.Mount{}
 
 	//nolint:varnamelen
-	for _, m := range task.Mounts {
-		volume, err := d.CreateVolume(ctx, m.Name, 0)
+	for _, m := range taskMounts {
+		switch m.Kind() {
+		case orchestra.MountTypeTmpfs:
+			mounts = append(mounts, mount.Mount{
+				Type:         mount.TypeTmpfs,
+				Target:       m.Path,
+				ReadOnly:     m.ReadOnly,
+				TmpfsOptions: &mount.TmpfsOptions{SizeBytes: int64(m.Size)},
+			})
+
+			continue
+		case orchestra.MountTypeHost:
+			if d.isLocal() {
+				mounts = append(mounts, mount.Mount{
+					Type:     mount.TypeBind,
+					Source:   m.HostPath,
+					Target:   m.Path,
+					ReadOnly: m.ReadOnly,
+				})
+
+				continue
+			}
+		case orchestra.MountTypeVolume:
+		}
+
+		volume, err := d.CreateVolume(ctx, m.Name, m.Size)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create volume: %w", err)
 		}
 
 		dockerVolume, _ := volume.(*DockerVolume)
 
+		// a remote daemon cannot see the host, so the directory is copied into a volume
+		if m.Kind() == orchestra.MountTypeHost {
+			err = dockerVolume.sync(ctx, m.HostPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		mounts = append(mounts, mount.Mount{
-			Type:   "volume",
-			Source: dockerVolume.volume.Name,
-			Target: m.Path,
+			Type:     mount.TypeVolume,
+			Source:   dockerVolume.volume.Name,
+			Target:   m.Path,
+			ReadOnly: m.ReadOnly,
 		})
 	}
 
+	return mounts, nil
+}
+
+func (d *Docker) RunContainer(ctx context.Context, task orchestra.Task) (orchestra.Container, error) {
+	return d.runContainer(ctx, task, nil)
+}
+
+// runContainer creates and starts the container of a task, or returns it when it already exists.
+// The aliases are names the container can be reached by on the network.
+func (d *Docker) runContainer(ctx context.Context, task orchestra.Task, aliases []string) (*DockerContainer, error) {
+	imageName, err := d.image(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	containerName := fmt.Sprintf("%s-%s", d.namespace, task.ID)
+
+	mounts, err := d.mounts(ctx, task.Mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	env := []string{}
+	for name, value := range task.Env {
+		env = append(env, name+"="+value)
+	}
+
+	networkName := d.currentNetwork()
+
+	var networking *network.NetworkingConfig
+	if networkName != "" {
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				networkName: {Aliases: aliases},
+			},
+		}
+	}
+
 	response, err := d.client.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image: task.Image,
-			Cmd:   task.Command,
-			Labels: map[string]string{
-				"orchestra.namespace": d.namespace,
-			},
+			Image:       imageName,
+			Cmd:         task.Command,
+			Env:         env,
+			Healthcheck: healthcheck(task.Readiness),
+			Labels:      d.resourceLabels(),
+			AttachStdin: task.Stdin != nil,
+			OpenStdin:   task.Stdin != nil,
+			StdinOnce:   task.Stdin != nil,
 		},
 		&container.HostConfig{
-			Mounts: mounts,
-		}, nil, nil,
+			Mounts:      mounts,
+			NetworkMode: container.NetworkMode(networkName),
+			Resources:   resources(task.Resources),
+		}, networking, nil,
 		containerName,
 	)
 	if err != nil && errdefs.IsConflict(err) {
@@ -136,23 +335,70 @@ func (d *Docker) RunContainer(ctx context.Context, task orchestra.Task) (orchest
 			return nil, fmt.Errorf("failed to find container by name %s: %w", containerName, ErrContainerNotFound)
 		}
 
+		// a container that was created, but never started, would otherwise stay pending
+		if containers[0].State == "created" {
+			return d.start(ctx, containers[0].ID, task)
+		}
+
 		return &DockerContainer{
-			id:     containers[0].ID,
-			client: d.client,
-			task:   task,
+			id:        containers[0].ID,
+			client:    d.client,
+			startedAt: time.Now(),
+			task:      task,
+			usage:     sampleUsage(d.client, containers[0].ID),
 		}, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
-	err = d.client.ContainerStart(ctx, response.ID, container.StartOptions{})
+	return d.start(ctx, response.ID, task)
+}
+
+// start starts a created container, with the stdin of the task.
+func (d *Docker) start(ctx context.Context, containerID string, task orchestra.Task) (*DockerContainer, error) {
+	// stdin has to be attached before the start, so none of it is missed
+	if task.Stdin != nil {
+		err := d.attachStdin(ctx, containerID, task.Stdin)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := d.client.ContainerStart(ctx, containerID, container.StartOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
 
 	return &DockerContainer{
-		id:     response.ID,
-		client: d.client,
-		task:   task,
+		id:        containerID,
+		client:    d.client,
+		startedAt: time.Now(),
+		task:      task,
+		usage:     sampleUsage(d.client, containerID),
 	}, nil
 }
+
+// attachStdin copies stdin into the container, closing it at the end,
+// so the command sees the end of input.
+func (d *Docker) attachStdin(ctx context.Context, containerID string, stdin io.Reader) error {
+	hijacked, err := d.client.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stdin:  true,
+		Stream: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach stdin: %w", err)
+	}
+
+	go func() {
+		defer hijacked.Close()
+
+		_, err := io.Copy(hijacked.Conn, stdin)
+		if err != nil {
+			d.logger.Warn("container.stdin", "id", containerID, "err", err)
+		}
+
+		_ = hijacked.CloseWrite()
+	}()
+
+	return nil
+}
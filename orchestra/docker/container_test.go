@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestContainerState(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		state    types.ContainerState
+		timedOut bool
+		expected orchestra.State
+		reason   string
+	}{
+		{
+			name:     "created",
+			state:    types.ContainerState{Status: "created"},
+			expected: orchestra.StatePending,
+		},
+		{
+			name:     "created with an error",
+			state:    types.ContainerState{Status: "created", Error: "no such file"},
+			expected: orchestra.StateErrored,
+			reason:   "no such file",
+		},
+		{
+			name:     "running",
+			state:    types.ContainerState{Status: "running", Running: true},
+			expected: orchestra.StateRunning,
+		},
+		{
+			name:     "succeeded",
+			state:    types.ContainerState{Status: "exited"},
+			expected: orchestra.StateSucceeded,
+		},
+		{
+			name:     "failed",
+			state:    types.ContainerState{Status: "exited", ExitCode: 2},
+			expected: orchestra.StateFailed,
+			reason:   "exited with code 2",
+		},
+		{
+			name:     "exited with an error",
+			state:    types.ContainerState{Status: "exited", ExitCode: 127, Error: "executable not found"},
+			expected: orchestra.StateErrored,
+			reason:   "executable not found",
+		},
+		{
+			name:     "oom killed",
+			state:    types.ContainerState{Status: "exited", ExitCode: 137, OOMKilled: true},
+			expected: orchestra.StateOOMKilled,
+			reason:   "killed for going over its memory limit",
+		},
+		{
+			name:     "dead",
+			state:    types.ContainerState{Status: "dead"},
+			expected: orchestra.StateErrored,
+			reason:   "container is dead",
+		},
+		{
+			name:     "timed out",
+			state:    types.ContainerState{Status: "exited", ExitCode: 137},
+			timedOut: true,
+			expected: orchestra.StateTimedOut,
+			reason:   "killed after timeout",
+		},
+	}
+
+	for _, example := range cases {
+		t.Run(example.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert := NewGomegaWithT(t)
+
+			status := &DockerContainerStatus{state: &example.state, timedOut: example.timedOut}
+			assert.Expect(status.State()).To(Equal(example.expected))
+			assert.Expect(status.Reason()).To(Equal(example.reason))
+			assert.Expect(status.IsDone()).To(Equal(example.expected.IsDone()))
+		})
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/volume"
@@ -12,12 +17,29 @@ import (
 )
 
 type Docker struct {
-	client    *client.Client
-	namespace string
+	client            *client.Client
+	defaultPullPolicy orchestra.PullPolicy
+	keep              bool
+	labels            map[string]string
+	logger            *slog.Logger
+	namespace         string
+	offline           bool
+	owner             orchestra.Owner
+
+	mutex       sync.Mutex
+	network     string
+	ownsNetwork bool
+
+	volumeSizes     bool
+	volumeSizesOnce sync.Once
 }
 
 // Close implements orchestra.Driver.
 func (d *Docker) Close() error {
+	if d.keep {
+		return nil
+	}
+
 	// find all containers in the namespace and remove them
 	_, err := d.client.ContainersPrune(context.Background(), filters.NewArgs(
 		filters.Arg("label", "orchestra.namespace="+d.namespace),
@@ -26,6 +48,11 @@ func (d *Docker) Close() error {
 		return fmt.Errorf("failed to prune containers: %w", err)
 	}
 
+	err = d.removeNetwork(context.Background())
+	if err != nil {
+		return err
+	}
+
 	// find all volumes in the namespace and remove them
 	volumes, err := d.client.VolumeList(context.Background(), volume.ListOptions{
 		Filters: filters.NewArgs(
@@ -45,31 +72,143 @@ func (d *Docker) Close() error {
 	return nil
 }
 
-func NewDocker(namespace string) (orchestra.Driver, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// isLocal is true when the daemon runs on this host, so host paths can be bind mounted.
+func (d *Docker) isLocal() bool {
+	host := d.client.DaemonHost()
+
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
+
+// NewDocker creates a driver for the docker host in the endpoint, otherwise the environment is used.
+// Supported params are `network` to attach containers to an existing network,
+// `tlscacert`, `tlscert`, and `tlskey` for hosts that require TLS,
+// `pull` for the default pull policy (`if-not-present` when not set),
+// and `offline` to never contact a registry, whatever the pull policy of a task.
+func NewDocker(options orchestra.Options) (orchestra.Driver, error) {
+	clientOpts := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	}
+
+	if options.Endpoint != "" {
+		clientOpts = append(clientOpts, client.WithHost(options.Endpoint))
+	}
+
+	params := options.Params
+	if params.Has("tlscacert") || params.Has("tlscert") || params.Has("tlskey") {
+		clientOpts = append(clientOpts, client.WithTLSClientConfig(
+			params.Get("tlscacert"),
+			params.Get("tlscert"),
+			params.Get("tlskey"),
+		))
+	}
+
+	pullPolicy, err := orchestra.ParsePullPolicy(params.Get("pull"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pull: %w", err)
+	}
+
+	if pullPolicy == "" {
+		pullPolicy = orchestra.PullIfNotPresent
+	}
+
+	offline := false
+	if params.Has("offline") {
+		offline, err = strconv.ParseBool(params.Get("offline"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse offline: %w", err)
+		}
+	}
+
+	cli, err := client.NewClientWithOpts(clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
 	return &Docker{
-		client:    cli,
-		namespace: namespace,
+		client:            cli,
+		defaultPullPolicy: pullPolicy,
+		keep:              options.Keep || options.Attach,
+		labels:            options.Labels,
+		logger:            slog.Default().With("orchestrator", "docker"),
+		namespace:         options.Namespace,
+		network:           params.Get("network"),
+		offline:           offline,
+		owner:             orchestra.CurrentOwner(),
 	}, nil
 }
 
+// resourceLabels are added to every container and volume, so they can be found by namespace and owner.
+func (d *Docker) resourceLabels() map[string]string {
+	labels := maps.Clone(d.labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	labels["orchestra.namespace"] = d.namespace
+	labels["orchestra.owner"] = d.owner.String()
+
+	return labels
+}
+
+// Capabilities implements orchestra.Driver.
+func (d *Docker) Capabilities() orchestra.Capabilities {
+	return orchestra.Capabilities{
+		Images:           true,
+		Networking:       true,
+		Privileged:       true,
+		ResourceLimits:   true,
+		Services:         true,
+		Stdin:            true,
+		TTY:              true,
+		Volumes:          true,
+		VolumeSizeLimits: d.supportsVolumeSizes(),
+	}
+}
+
+// supportsVolumeSizes is true when the data root of the daemon is on XFS,
+// which the local driver needs for the quotas of sized volumes.
+// It cannot tell whether project quotas are enabled, which CreateVolume reports instead.
+func (d *Docker) supportsVolumeSizes() bool {
+	d.volumeSizesOnce.Do(func() {
+		info, err := d.client.Info(context.Background())
+		if err != nil {
+			return
+		}
+
+		for _, status := range info.DriverStatus {
+			if status[0] == "Backing Filesystem" && status[1] == "xfs" {
+				d.volumeSizes = true
+			}
+		}
+	})
+
+	return d.volumeSizes
+}
+
 func (d *Docker) Name() string {
 	return "docker"
 }
 
-var ErrContainerNotFound = errors.New("container not found")
+var (
+	ErrContainerNotFound = errors.New("container not found")
+	ErrUnknownResource   = errors.New("unknown resource")
+)
 
 func init() {
 	orchestra.Add("docker", NewDocker)
 }
 
 var (
-	_ orchestra.Driver          = &Docker{}
-	_ orchestra.Container       = &DockerContainer{}
-	_ orchestra.ContainerStatus = &DockerContainerStatus{}
-	_ orchestra.Volume          = &DockerVolume{}
+	_ orchestra.Collector        = &Docker{}
+	_ orchestra.Driver           = &Docker{}
+	_ orchestra.Hijacker         = &Docker{}
+	_ orchestra.ImageBuilder     = &Docker{}
+	_ orchestra.ImagePuller      = &Docker{}
+	_ orchestra.ServiceDriver    = &Docker{}
+	_ orchestra.Container        = &DockerContainer{}
+	_ orchestra.ContainerStatus  = &DockerContainerStatus{}
+	_ orchestra.ServiceContainer = &DockerService{}
+	_ orchestra.UsageReporter    = &DockerContainer{}
+	_ orchestra.Volume           = &DockerVolume{}
 )
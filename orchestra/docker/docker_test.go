@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestPullPolicy(t *testing.T) {
+	t.Parallel()
+
+	driver := func(assert *WithT, params url.Values) *Docker {
+		client, err := NewDocker(orchestra.Options{Namespace: "test", Params: params})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		docker, _ := client.(*Docker)
+
+		return docker
+	}
+
+	t.Run("defaults to if-not-present", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		docker := driver(assert, url.Values{})
+		assert.Expect(docker.pullPolicy("")).To(Equal(orchestra.PullIfNotPresent))
+		assert.Expect(docker.pullPolicy(orchestra.PullAlways)).To(Equal(orchestra.PullAlways))
+	})
+
+	t.Run("the pull param sets the default", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		docker := driver(assert, url.Values{"pull": {"always"}})
+		assert.Expect(docker.pullPolicy("")).To(Equal(orchestra.PullAlways))
+		assert.Expect(docker.pullPolicy(orchestra.PullNever)).To(Equal(orchestra.PullNever))
+	})
+
+	t.Run("offline never pulls", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		docker := driver(assert, url.Values{"offline": {"true"}, "pull": {"always"}})
+		assert.Expect(docker.pullPolicy("")).To(Equal(orchestra.PullNever))
+		assert.Expect(docker.pullPolicy(orchestra.PullAlways)).To(Equal(orchestra.PullNever))
+	})
+
+	t.Run("rejects invalid params", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := NewDocker(orchestra.Options{Params: url.Values{"pull": {"sometimes"}}})
+		assert.Expect(err).To(MatchError(orchestra.ErrInvalidPullPolicy))
+
+		_, err = NewDocker(orchestra.Options{Params: url.Values{"offline": {"maybe"}}})
+		assert.Expect(err).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// hijackShell is interactive without a TTY, so it works whether or not the caller has a terminal.
+var hijackShell = []string{"sh", "-i"}
+
+// Hijack implements orchestra.Hijacker.
+// A running container gets a shell exec'd into it. A stopped container cannot be exec'd into,
+// so the shell runs in a snapshot of it instead, with the same mounts, environment, and network.
+func (d *Docker) Hijack(ctx context.Context, containerID string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	inspection, err := d.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	if inspection.State.Running {
+		return execCommand(ctx, d.client, containerID, hijackShell, stdin, stdout, stderr)
+	}
+
+	snapshot, err := d.client.ContainerCommit(ctx, containerID, container.CommitOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to snapshot container: %w", err)
+	}
+
+	defer func() {
+		_, _ = d.client.ImageRemove(context.Background(), snapshot.ID, image.RemoveOptions{Force: true, PruneChildren: true})
+	}()
+
+	mounts := []mount.Mount{}
+
+	for _, point := range inspection.Mounts {
+		source := point.Source
+		if point.Type == mount.TypeVolume {
+			source = point.Name
+		}
+
+		// tmpfs contents do not outlive the container, so they cannot be carried over
+		if point.Type == mount.TypeTmpfs {
+			continue
+		}
+
+		mounts = append(mounts, mount.Mount{
+			Type:     point.Type,
+			Source:   source,
+			Target:   point.Destination,
+			ReadOnly: !point.RW,
+		})
+	}
+
+	response, err := d.client.ContainerCreate(
+		ctx,
+		&container.Config{
+			AttachStderr: true,
+			AttachStdin:  true,
+			AttachStdout: true,
+			Entrypoint:   hijackShell,
+			Env:          inspection.Config.Env,
+			Image:        snapshot.ID,
+			Labels:       d.resourceLabels(),
+			OpenStdin:    true,
+			StdinOnce:    true,
+			WorkingDir:   inspection.Config.WorkingDir,
+		},
+		&container.HostConfig{
+			Mounts:      mounts,
+			NetworkMode: inspection.HostConfig.NetworkMode,
+		}, nil, nil, "",
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create hijack container: %w", err)
+	}
+
+	defer func() {
+		_ = d.client.ContainerRemove(context.Background(), response.ID, container.RemoveOptions{Force: true})
+	}()
+
+	hijacked, err := d.client.ContainerAttach(ctx, response.ID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to hijack container: %w", err)
+	}
+	defer hijacked.Close()
+
+	waitChan, errChan := d.client.ContainerWait(ctx, response.ID, container.WaitConditionNextExit)
+
+	err = d.client.ContainerStart(ctx, response.ID, container.StartOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start hijack container: %w", err)
+	}
+
+	err = stream(hijacked, stdin, stdout, stderr)
+	if err != nil {
+		return 0, err
+	}
+
+	select {
+	case result := <-waitChan:
+		return int(result.StatusCode), nil
+	case err := <-errChan:
+		return 0, fmt.Errorf("failed to wait for hijack container: %w", err)
+	}
+}
+
+// execCommand runs a command in a running container, returning its exit code.
+func execCommand(
+	ctx context.Context,
+	client *client.Client,
+	containerID string,
+	command []string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+) (int, error) {
+	created, err := client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		AttachStderr: true,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		Cmd:          command,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	hijacked, err := client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer hijacked.Close()
+
+	err = stream(hijacked, stdin, stdout, stderr)
+	if err != nil {
+		return 0, err
+	}
+
+	inspection, err := client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return inspection.ExitCode, nil
+}
+
+// stream copies stdin to an attached connection, closing its write side at the end of stdin,
+// and demultiplexes its output until it is closed.
+func stream(hijacked types.HijackedResponse, stdin io.Reader, stdout, stderr io.Writer) error {
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(hijacked.Conn, stdin)
+			_ = hijacked.CloseWrite()
+		}()
+	}
+
+	_, err := stdcopy.StdCopy(stdout, stderr, hijacked.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to copy output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+var (
+	ErrImageNotLoaded  = errors.New("image was not loaded")
+	ErrImageNotPresent = errors.New("image is not present")
+)
+
+// archivePrefixes are the image references that are loaded from a tarball on the host,
+// instead of being pulled from a registry.
+var archivePrefixes = []string{"oci-archive:", "docker-archive:"}
+
+// archiveCacheRepository is the repository loaded archives are tagged in, by the digest of the file,
+// so the same archive is only loaded once.
+const archiveCacheRepository = "orchestra-archive"
+
+// image makes sure the image of a task is present and returns the reference to create the container with.
+func (d *Docker) image(ctx context.Context, task orchestra.Task) (string, error) {
+	for _, prefix := range archivePrefixes {
+		if path, found := strings.CutPrefix(task.Image, prefix); found {
+			return d.loadImage(ctx, path)
+		}
+	}
+
+	return task.Image, d.pullImage(ctx, task.Image, task.PullPolicy, task.RegistryAuth)
+}
+
+// loadImage loads an image archive, unless an archive with the same digest has been loaded before.
+func (d *Docker) loadImage(ctx context.Context, path string) (string, error) {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return "", err
+	}
+
+	reference := archiveCacheRepository + ":" + digest
+
+	_, _, err = d.client.ImageInspectWithRaw(ctx, reference)
+	if err == nil {
+		return reference, nil
+	}
+
+	if !errdefs.IsNotFound(err) {
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image archive: %w", err)
+	}
+	defer file.Close()
+
+	response, err := d.client.ImageLoad(ctx, file, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to load image archive: %w", err)
+	}
+	defer response.Body.Close()
+
+	loaded, err := loadedImage(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to load image archive %s: %w", path, err)
+	}
+
+	err = d.client.ImageTag(ctx, loaded, reference)
+	if err != nil {
+		return "", fmt.Errorf("failed to tag loaded image: %w", err)
+	}
+
+	return reference, nil
+}
+
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image archive: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash image archive: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadedImage returns the first image reported by the output of a load.
+func loadedImage(reader io.Reader) (string, error) {
+	decoder := json.NewDecoder(reader)
+
+	for {
+		var message jsonmessage.JSONMessage
+
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return "", ErrImageNotLoaded
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to decode output: %w", err)
+		}
+
+		if message.Error != nil {
+			return "", message.Error
+		}
+
+		for _, prefix := range []string{"Loaded image ID: ", "Loaded image: "} {
+			if loaded, found := strings.CutPrefix(strings.TrimSpace(message.Stream), prefix); found {
+				return loaded, nil
+			}
+		}
+	}
+}
+
+// pullPolicy is the policy of a task, where offline mode always wins.
+func (d *Docker) pullPolicy(policy orchestra.PullPolicy) orchestra.PullPolicy {
+	if d.offline {
+		return orchestra.PullNever
+	}
+
+	if policy == "" {
+		return d.defaultPullPolicy
+	}
+
+	return policy
+}
+
+// pullImage makes sure the image is present, contacting the registry only when the policy allows it.
+func (d *Docker) pullImage(
+	ctx context.Context,
+	name string,
+	policy orchestra.PullPolicy,
+	auth *orchestra.RegistryAuth,
+) error {
+	policy = d.pullPolicy(policy)
+
+	if policy != orchestra.PullAlways {
+		_, _, err := d.client.ImageInspectWithRaw(ctx, name)
+		if err == nil {
+			return nil
+		}
+
+		if !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to inspect image: %w", err)
+		}
+
+		if policy == orchestra.PullNever {
+			return fmt.Errorf("%w: %s cannot be pulled with the %q pull policy", ErrImageNotPresent, name, policy)
+		}
+	}
+
+	encodedAuth, err := registryAuth(name, auth)
+	if err != nil {
+		return err
+	}
+
+	reader, err := d.client.ImagePull(ctx, name, image.PullOptions{RegistryAuth: encodedAuth})
+	if err != nil {
+		return fmt.Errorf("failed to initiate pull image: %w", err)
+	}
+	defer reader.Close()
+
+	err = pullProgress(reader, d.logger.With("image", name))
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	return nil
+}
+
+// PullImage implements orchestra.ImagePuller.
+func (d *Docker) PullImage(ctx context.Context, task orchestra.Task) error {
+	_, err := d.image(ctx, task)
+
+	return err
+}
+
+// progressInterval limits how often the download progress of a pull is logged.
+const progressInterval = 2 * time.Second
+
+// pullProgress logs each status of the layers of a pull, and the download progress every progressInterval,
+// so long pulls do not look like they are hanging.
+func pullProgress(reader io.Reader, logger *slog.Logger) error {
+	decoder := json.NewDecoder(reader)
+	statuses := map[string]string{}
+	lastProgress := time.Time{}
+
+	for {
+		var message jsonmessage.JSONMessage
+
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to decode output: %w", err)
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+
+		if statuses[message.ID] != message.Status {
+			statuses[message.ID] = message.Status
+
+			logger.Info("image.pull", "layer", message.ID, "status", message.Status)
+
+			continue
+		}
+
+		if message.Progress != nil && message.Progress.Total > 0 && time.Since(lastProgress) >= progressInterval {
+			lastProgress = time.Now()
+
+			logger.Info("image.pull.progress",
+				"layer", message.ID,
+				"status", message.Status,
+				"current", message.Progress.Current,
+				"total", message.Progress.Total,
+			)
+		}
+	}
+}
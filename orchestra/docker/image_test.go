@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLoadedImage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("a tagged image", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		loaded, err := loadedImage(strings.NewReader(`{"stream":"Loaded image: busybox:latest\n"}`))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(loaded).To(Equal("busybox:latest"))
+	})
+
+	t.Run("an untagged image", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		loaded, err := loadedImage(strings.NewReader(
+			`{"status":"Loading layer","progressDetail":{"current":512,"total":1024}}` + "\n" +
+				`{"stream":"Loaded image ID: sha256:abc123\n"}` + "\n" +
+				`{"stream":"Loaded image: other:latest\n"}`,
+		))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(loaded).To(Equal("sha256:abc123"))
+	})
+
+	t.Run("an error from the daemon", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := loadedImage(strings.NewReader(`{"errorDetail":{"message":"invalid tar header"},"error":"invalid tar header"}`))
+		assert.Expect(err).To(MatchError(ContainSubstring("invalid tar header")))
+	})
+
+	t.Run("nothing was loaded", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := loadedImage(strings.NewReader(`{"stream":"\n"}`))
+		assert.Expect(err).To(MatchError(ErrImageNotLoaded))
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		_, err := loadedImage(strings.NewReader(`not json`))
+		assert.Expect(err).To(HaveOccurred())
+		assert.Expect(err).NotTo(MatchError(ErrImageNotLoaded))
+	})
+}
+
+func TestFileDigest(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	assert.Expect(os.WriteFile(path, []byte("hello"), 0o600)).To(Succeed())
+
+	digest, err := fileDigest(path)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(digest).To(Equal("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+
+	_, err = fileDigest(filepath.Join(t.TempDir(), "missing.tar"))
+	assert.Expect(err).To(HaveOccurred())
+}
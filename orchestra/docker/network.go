@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+// ensureNetwork creates a user-defined network for the namespace,
+// so containers can reach services by name. A network from the options is used as is.
+func (d *Docker) ensureNetwork(ctx context.Context) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.network != "" {
+		return d.network, nil
+	}
+
+	_, err := d.client.NetworkCreate(ctx, d.namespace, network.CreateOptions{
+		Driver: "bridge",
+		Labels: d.resourceLabels(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create network: %w", err)
+	}
+
+	d.network = d.namespace
+	d.ownsNetwork = true
+
+	return d.network, nil
+}
+
+func (d *Docker) currentNetwork() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.network
+}
+
+func (d *Docker) removeNetwork(ctx context.Context) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.ownsNetwork {
+		return nil
+	}
+
+	err := d.client.NetworkRemove(ctx, d.network)
+	if err != nil {
+		return fmt.Errorf("failed to remove network %s: %w", d.network, err)
+	}
+
+	d.network = ""
+	d.ownsNetwork = false
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// healthcheck converts a readiness probe into a docker healthcheck.
+// TCP and HTTP probes run inside the container, so they rely on `nc` and `wget` being in the image.
+func healthcheck(probe *orchestra.Probe) *container.HealthConfig {
+	if probe == nil {
+		return nil
+	}
+
+	withDefaults := probe.WithDefaults()
+
+	var test []string
+
+	switch {
+	case len(withDefaults.Exec) > 0:
+		test = append([]string{"CMD"}, withDefaults.Exec...)
+	case withDefaults.HTTPGet != nil:
+		path := withDefaults.HTTPGet.Path
+		if path == "" {
+			path = "/"
+		}
+
+		test = []string{"CMD-SHELL", fmt.Sprintf(
+			"wget -q -O /dev/null http://127.0.0.1:%d%s || exit 1",
+			withDefaults.HTTPGet.Port, path,
+		)}
+	case withDefaults.TCPPort > 0:
+		test = []string{"CMD-SHELL", fmt.Sprintf("nc -z 127.0.0.1 %d || exit 1", withDefaults.TCPPort)}
+	default:
+		return nil
+	}
+
+	return &container.HealthConfig{
+		Test:     test,
+		Interval: withDefaults.Interval,
+		Timeout:  withDefaults.Timeout,
+		Retries:  withDefaults.Retries,
+	}
+}
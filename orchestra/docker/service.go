@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+type DockerService struct {
+	*DockerContainer
+	name string
+}
+
+// Host implements orchestra.ServiceContainer.
+// Services are reachable by their name on the network of the namespace.
+func (d *DockerService) Host() string {
+	return d.name
+}
+
+// Port implements orchestra.ServiceContainer.
+func (d *DockerService) Port(port int) int {
+	return port
+}
+
+// RunService implements orchestra.ServiceDriver.
+func (d *Docker) RunService(ctx context.Context, service orchestra.Service) (orchestra.ServiceContainer, error) {
+	_, err := d.ensureNetwork(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run service: %w", err)
+	}
+
+	container, err := d.runContainer(ctx, service.Task, []string{service.Name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run service: %w", err)
+	}
+
+	return &DockerService{
+		DockerContainer: container,
+		name:            service.Name,
+	}, nil
+}
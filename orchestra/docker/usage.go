@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// usageSampler reads the stats of a container while it runs,
+// as the stats are gone once it has stopped.
+type usageSampler struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	mutex  sync.Mutex
+	usage  orchestra.Usage
+}
+
+// usageInterval is how often the stats are sampled,
+// a task that exits sooner than that may have no usage.
+const usageInterval = 100 * time.Millisecond
+
+// sampleUsage samples the stats of a started container until it stops.
+// Samples are one-shot, as a stream only has its first stats after a second,
+// and does not end when the container stops.
+func sampleUsage(client *client.Client, containerID string) *usageSampler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sampler := &usageSampler{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sampler.done)
+
+		waitChan, errChan := client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+
+		ticker := time.NewTicker(usageInterval)
+		defer ticker.Stop()
+
+		for {
+			sampler.sample(ctx, client, containerID)
+
+			select {
+			case <-waitChan:
+				return
+			case <-errChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return sampler
+}
+
+func (s *usageSampler) sample(ctx context.Context, client *client.Client, containerID string) {
+	stats, err := client.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		return
+	}
+	defer stats.Body.Close()
+
+	var response container.StatsResponse
+
+	err = json.NewDecoder(stats.Body).Decode(&response)
+	if err != nil {
+		return
+	}
+
+	s.record(response)
+}
+
+func (s *usageSampler) record(response container.StatsResponse) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// a stopped container reports empty stats, which would reset the totals
+	if response.CPUStats.CPUUsage.TotalUsage == 0 {
+		return
+	}
+
+	s.usage.CPUTime = time.Duration(response.CPUStats.CPUUsage.TotalUsage) //nolint:gosec
+	s.usage.PeakMemory = max(s.usage.PeakMemory, response.MemoryStats.Usage, response.MemoryStats.MaxUsage)
+
+	var readBytes, writeBytes uint64
+
+	for _, entry := range response.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "read", "Read":
+			readBytes += entry.Value
+		case "write", "Write":
+			writeBytes += entry.Value
+		}
+	}
+
+	s.usage.ReadBytes = max(s.usage.ReadBytes, readBytes)
+	s.usage.WriteBytes = max(s.usage.WriteBytes, writeBytes)
+}
+
+// Usage implements orchestra.UsageReporter.
+// Once the container is done, it waits for the sampling to end, which it does when the container stops.
+func (d *DockerContainer) Usage(ctx context.Context) (orchestra.Usage, error) {
+	status, err := d.Status(ctx)
+	if err != nil {
+		return orchestra.Usage{}, err
+	}
+
+	if status.IsDone() {
+		select {
+		case <-d.usage.done:
+		case <-ctx.Done():
+			return orchestra.Usage{}, fmt.Errorf("failed to wait for usage: %w", ctx.Err())
+		}
+	}
+
+	d.usage.mutex.Lock()
+	defer d.usage.mutex.Unlock()
+
+	return d.usage.usage, nil
+}
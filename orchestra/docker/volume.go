@@ -2,18 +2,192 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
 )
 
 type DockerVolume struct {
 	client *client.Client
+	driver *Docker
 	volume volume.Volume
 }
 
+// helperImage is used to reach the contents of a volume, as they can only be copied through a container.
+const helperImage = "busybox"
+
+const helperRoot = "/volume"
+
+var (
+	ErrHelperFailed = errors.New("helper container failed")
+	ErrInvalidPath  = errors.New("path is not in the volume")
+)
+
+// helperPath returns where path is in the helper container.
+func helperPath(name string) (string, error) {
+	joined := path.Join(helperRoot, name)
+	if joined != helperRoot && !strings.HasPrefix(joined, helperRoot+"/") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPath, name)
+	}
+
+	return joined, nil
+}
+
+// helper creates a container, with the volume mounted, that runs command.
+// The container is only started when there is a command.
+func (d *DockerVolume) helper(ctx context.Context, command []string) (string, error) {
+	err := d.driver.pullImage(ctx, helperImage, "", nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := d.client.ContainerCreate(
+		ctx,
+		&container.Config{
+			Image:  helperImage,
+			Cmd:    command,
+			Labels: d.driver.resourceLabels(),
+		},
+		&container.HostConfig{
+			Mounts: []mount.Mount{{
+				Type:   "volume",
+				Source: d.volume.Name,
+				Target: helperRoot,
+			}},
+		}, nil, nil, "",
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create helper container: %w", err)
+	}
+
+	if len(command) == 0 {
+		return response.ID, nil
+	}
+
+	waitChan, errChan := d.client.ContainerWait(ctx, response.ID, container.WaitConditionNextExit)
+
+	err = d.client.ContainerStart(ctx, response.ID, container.StartOptions{})
+	if err != nil {
+		d.removeHelper(response.ID)
+
+		return "", fmt.Errorf("failed to start helper container: %w", err)
+	}
+
+	select {
+	case result := <-waitChan:
+		if result.StatusCode != 0 {
+			d.removeHelper(response.ID)
+
+			return "", fmt.Errorf("%w: helper container exited with %d", ErrHelperFailed, result.StatusCode)
+		}
+	case err := <-errChan:
+		d.removeHelper(response.ID)
+
+		return "", fmt.Errorf("failed to wait for helper container: %w", err)
+	}
+
+	return response.ID, nil
+}
+
+func (d *DockerVolume) removeHelper(id string) {
+	_ = d.client.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+}
+
+// Export implements orchestra.Volume.
+func (d *DockerVolume) Export(ctx context.Context, name string) (io.ReadCloser, error) {
+	source, err := helperPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := d.helper(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the trailing `/.` copies the contents of the directory, not the directory itself
+	reader, _, err := d.client.CopyFromContainer(ctx, id, source+"/.")
+	if err != nil {
+		d.removeHelper(id)
+
+		return nil, fmt.Errorf("failed to copy from volume: %w", err)
+	}
+
+	return &helperReader{
+		ReadCloser: reader,
+		remove:     func() { d.removeHelper(id) },
+	}, nil
+}
+
+// helperReader removes the helper container once the export has been read.
+type helperReader struct {
+	io.ReadCloser
+	remove func()
+}
+
+func (h *helperReader) Close() error {
+	defer h.remove()
+
+	return h.ReadCloser.Close() //nolint:wrapcheck
+}
+
+// sync copies a host directory into the volume, skipping what git ignores.
+// The volume is emptied first, so files removed on the host are removed from it too.
+func (d *DockerVolume) sync(ctx context.Context, hostPath string) error {
+	id, err := d.helper(ctx, []string{"find", helperRoot, "-mindepth", "1", "-delete"})
+	if err != nil {
+		return fmt.Errorf("failed to empty volume for %s: %w", hostPath, err)
+	}
+
+	d.removeHelper(id)
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(archive.Tar(hostPath, writer, archive.GitIgnore(hostPath)))
+	}()
+	defer reader.Close()
+
+	err = d.Import(ctx, "", reader)
+	if err != nil {
+		return fmt.Errorf("failed to sync %s: %w", hostPath, err)
+	}
+
+	return nil
+}
+
+// Import implements orchestra.Volume.
+func (d *DockerVolume) Import(ctx context.Context, name string, tar io.Reader) error {
+	destination, err := helperPath(name)
+	if err != nil {
+		return err
+	}
+
+	id, err := d.helper(ctx, []string{"mkdir", "-p", destination})
+	if err != nil {
+		return err
+	}
+	defer d.removeHelper(id)
+
+	err = d.client.CopyToContainer(ctx, id, destination, tar, container.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to copy to volume: %w", err)
+	}
+
+	return nil
+}
+
 // Cleanup implements orchestra.Volume.
 func (d *DockerVolume) Cleanup(ctx context.Context) error {
 	err := d.client.VolumeRemove(ctx, d.volume.Name, true)
@@ -24,19 +198,54 @@ func (d *DockerVolume) Cleanup(ctx context.Context) error {
 	return nil
 }
 
+// CreateVolume implements orchestra.Driver.
+// Volumes with a size use the quota of the local driver, which needs the daemon's data root
+// on XFS with project quotas, otherwise they are unsupported.
+// A volume that exists is returned, unless it was created with a different size.
 func (d *Docker) CreateVolume(ctx context.Context, name string, size int) (orchestra.Volume, error) {
-	volume, err := d.client.VolumeCreate(ctx, volume.CreateOptions{
-		Name: fmt.Sprintf("%s-%s", d.namespace, name),
-		Labels: map[string]string{
-			"orchestra.namespace": d.namespace,
-		},
-	})
+	volumeName := fmt.Sprintf("%s-%s", d.namespace, name)
+
+	existing, err := d.client.VolumeInspect(ctx, volumeName)
+	if err == nil {
+		if size > 0 && existing.Options["size"] != strconv.Itoa(size) {
+			return nil, fmt.Errorf("%w: %s", orchestra.ErrVolumeSizeMismatch, name)
+		}
+
+		return &DockerVolume{
+			client: d.client,
+			driver: d,
+			volume: existing,
+		}, nil
+	}
+
+	if !errdefs.IsNotFound(err) {
+		return nil, fmt.Errorf("could not inspect volume: %w", err)
+	}
+
+	options := volume.CreateOptions{
+		Name:   volumeName,
+		Labels: d.resourceLabels(),
+	}
+
+	if size > 0 {
+		options.Driver = "local"
+		options.DriverOpts = map[string]string{
+			"size": strconv.Itoa(size),
+		}
+	}
+
+	volume, err := d.client.VolumeCreate(ctx, options)
+	if err != nil && size > 0 && strings.Contains(err.Error(), "quota") {
+		return nil, fmt.Errorf("volume sizes are %w: %w", orchestra.ErrUnsupportedCapability, err)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("could not create volume: %w", err)
 	}
 
 	return &DockerVolume{
 		client: d.client,
+		driver: d,
 		volume: volume,
 	}, nil
 }
@@ -1,6 +1,6 @@
 package orchestra
 
-type InitFunc func(string) (Driver, error)
+type InitFunc func(Options) (Driver, error)
 
 var drivers = map[string]InitFunc{}
 
@@ -1,13 +1,17 @@
 package orchestra_test
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
 	_ "github.com/jtarchie/ci/orchestra/docker"
 	_ "github.com/jtarchie/ci/orchestra/native"
 	. "github.com/onsi/gomega"
@@ -18,7 +22,7 @@ func TestDrivers(t *testing.T) {
 		t.Run(name+" exit code failed", func(t *testing.T) {
 			assert := NewGomegaWithT(t)
 
-			client, err := init("test")
+			client, err := init(orchestra.Options{Namespace: "test"})
 			assert.Expect(err).NotTo(HaveOccurred())
 			defer client.Close()
 
@@ -50,6 +54,13 @@ func TestDrivers(t *testing.T) {
 				return status.IsDone() && status.ExitCode() == 1
 			}).Should(BeTrue())
 
+			status, err := container.Status(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(status.State()).To(Equal(orchestra.StateFailed))
+			assert.Expect(status.Reason()).NotTo(BeEmpty())
+			assert.Expect(status.StartedAt()).NotTo(BeZero())
+			assert.Expect(status.FinishedAt()).To(BeTemporally(">=", status.StartedAt()))
+
 			err = client.Close()
 			assert.Expect(err).NotTo(HaveOccurred())
 		})
@@ -57,7 +68,7 @@ func TestDrivers(t *testing.T) {
 		t.Run(name+" happy path", func(t *testing.T) {
 			assert := NewGomegaWithT(t)
 
-			client, err := init("test")
+			client, err := init(orchestra.Options{Namespace: "test"})
 			assert.Expect(err).NotTo(HaveOccurred())
 			defer client.Close()
 
@@ -94,6 +105,7 @@ func TestDrivers(t *testing.T) {
 			}, "90s").Should(BeTrue())
 
 			// running a container should be deterministic and idempotent
+			containerID := container.ID()
 			container, err = client.RunContainer(
 				context.Background(),
 				orchestra.Task{
@@ -103,6 +115,7 @@ func TestDrivers(t *testing.T) {
 				},
 			)
 			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(container.ID()).To(Equal(containerID))
 
 			assert.Eventually(func() bool {
 				status, err := container.Status(context.Background())
@@ -126,10 +139,568 @@ func TestDrivers(t *testing.T) {
 			assert.Expect(err).NotTo(HaveOccurred())
 		})
 
+		t.Run(name+" host mount", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			source := t.TempDir()
+			err = os.WriteFile(filepath.Join(source, "hello"), []byte("world"), 0o600)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "cat ./input/hello"},
+					Mounts: orchestra.Mounts{
+						{Name: "input", Path: "/input", HostPath: source},
+					},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() == 0
+			}, "10s").Should(BeTrue())
+
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			err = container.Logs(context.Background(), stdout, stderr)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(stdout.String()).To(ContainSubstring("world"))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" read-only and tmpfs mounts", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			run := func(command string, mounts orchestra.Mounts) string {
+				taskID, err := uuid.NewV7()
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				container, err := client.RunContainer(
+					context.Background(),
+					orchestra.Task{
+						ID:      taskID.String(),
+						Image:   "alpine",
+						Command: []string{"sh", "-c", command},
+						Mounts:  mounts,
+					},
+				)
+				assert.Expect(err).NotTo(HaveOccurred())
+				defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+				assert.Eventually(func() bool {
+					status, err := container.Status(context.Background())
+					assert.Expect(err).NotTo(HaveOccurred())
+
+					return status.IsDone() && status.ExitCode() == 0
+				}, "10s").Should(BeTrue())
+
+				stdout, stderr := &strings.Builder{}, &strings.Builder{}
+				err = container.Logs(context.Background(), stdout, stderr)
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return stdout.String()
+			}
+
+			run("echo original > ./data/file", orchestra.Mounts{
+				{Name: "data", Path: "/data"},
+			})
+
+			stdout := run("echo changed > ./data/file; echo scratch > ./scratch/file && cat ./scratch/file", orchestra.Mounts{
+				{Name: "data", Path: "/data", ReadOnly: true},
+				{Name: "scratch", Path: "/scratch", Type: orchestra.MountTypeTmpfs},
+			})
+			assert.Expect(stdout).To(ContainSubstring("scratch"))
+
+			stdout = run("cat ./data/file", orchestra.Mounts{
+				{Name: "data", Path: "/data"},
+			})
+			assert.Expect(stdout).To(ContainSubstring("original"))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" timeout", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sleep", "60"},
+					Timeout: time.Second,
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() orchestra.State {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.State()
+			}, "10s").Should(Equal(orchestra.StateTimedOut))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" usage", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "sleep 0.5"},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			reporter, ok := container.(orchestra.UsageReporter)
+			if !ok {
+				t.Skip("usage is not reported")
+			}
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone()
+			}, "10s").Should(BeTrue())
+
+			// the usage of a finished task is known without waiting
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			usage, err := reporter.Usage(ctx)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(usage.PeakMemory).To(BeNumerically(">", 0))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" background processes", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "sleep 60 & echo started"},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			// the task is done when its command exits, not when what it left in the background does
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() == 0
+			}, "3s").Should(BeTrue())
+
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			err = container.Logs(context.Background(), stdout, stderr)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(stdout.String()).To(ContainSubstring("started"))
+
+			err = container.Cleanup(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" stdin", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			if !client.Capabilities().Stdin {
+				t.Skip("stdin is not supported")
+			}
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "cat && echo done"},
+					Stdin:   strings.NewReader("from stdin\n"),
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() == 0
+			}, "10s").Should(BeTrue())
+
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			err = container.Logs(context.Background(), stdout, stderr)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(stdout.String()).To(ContainSubstring("from stdin\ndone"))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" hijack", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			namespace, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			client, err := init(orchestra.Options{Namespace: "test-" + namespace.String()})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      namespace.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "echo kept > file && exit 1"},
+					Env:     map[string]string{"TASK": "hijacked"},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone()
+			}, "10s").Should(BeTrue())
+
+			// like `ci hijack`, from another driver attached to the namespace of the run
+			attached, err := init(orchestra.Options{Attach: true, Namespace: "test-" + namespace.String()})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer attached.Close()
+
+			hijacker, ok := attached.(orchestra.Hijacker)
+			assert.Expect(ok).To(BeTrue())
+
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			code, err := hijacker.Hijack(
+				context.Background(),
+				container.ID(),
+				strings.NewReader("echo task=$TASK && cat file && exit 3\n"),
+				stdout, stderr,
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(code).To(Equal(3))
+			assert.Expect(stdout.String()).To(ContainSubstring("task=hijacked\nkept"))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" resource limits", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			if !client.Capabilities().ResourceLimits {
+				t.Skip("resource limits are not supported")
+			}
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"echo", "limited"},
+					Resources: orchestra.Resources{
+						Memory: 64 * 1024 * 1024,
+						Pids:   100,
+					},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() == 0 && !status.IsOOMKilled()
+			}, "10s").Should(BeTrue())
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" service", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			// probes are made from the host, which cannot reach a task without a network
+			if !client.Capabilities().Services {
+				t.Skip("services are not supported")
+			}
+
+			driver, ok := client.(orchestra.ServiceDriver)
+			assert.Expect(ok).To(BeTrue())
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			service, err := driver.RunService(
+				context.Background(),
+				orchestra.Service{
+					Task: orchestra.Task{
+						ID:      taskID.String(),
+						Image:   "alpine",
+						Command: []string{"sh", "-c", "sleep 1 && touch ready && sleep 60"},
+						Readiness: &orchestra.Probe{
+							Exec:     []string{"test", "-e", "ready"},
+							Interval: 100 * time.Millisecond,
+						},
+					},
+					Name:  "database",
+					Ports: []int{5432},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(service.Host()).NotTo(BeEmpty())
+			assert.Expect(service.Port(5432)).To(BeNumerically(">", 0))
+
+			// running a service should be idempotent, like a container
+			again, err := driver.RunService(
+				context.Background(),
+				orchestra.Service{
+					Task:  orchestra.Task{ID: taskID.String(), Image: "alpine", Command: []string{"sleep", "60"}},
+					Name:  "database",
+					Ports: []int{5432},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(again.ID()).To(Equal(service.ID()))
+			assert.Expect(again.Port(5432)).To(Equal(service.Port(5432)))
+
+			status, err := service.Status(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(status.IsReady()).To(BeFalse())
+
+			assert.Eventually(func() bool {
+				status, err := service.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsReady()
+			}, "10s").Should(BeTrue())
+
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			code, err := service.Exec(context.Background(), []string{"sh", "-c", "test -e ready && echo exec"}, nil, stdout, stderr)
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(code).To(Equal(0))
+			assert.Expect(stdout.String()).To(ContainSubstring("exec"))
+
+			assert.Consistently(func() bool {
+				status, err := service.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone()
+			}).Should(BeFalse())
+
+			err = service.Cleanup(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" volume import and export", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			source := t.TempDir()
+			err = os.WriteFile(filepath.Join(source, "hello"), []byte("world"), 0o600)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			tarball := &bytes.Buffer{}
+			err = archive.Tar(source, tarball, nil)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			volume, err := client.CreateVolume(context.Background(), "data", 0)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			err = volume.Import(context.Background(), "seed", tarball)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "mkdir -p ./data/out && cp ./data/seed/hello ./data/out/copy"},
+					Mounts: orchestra.Mounts{
+						{Name: "data", Path: "/data"},
+					},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() == 0
+			}, "10s").Should(BeTrue())
+
+			reader, err := volume.Export(context.Background(), "out")
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+
+			destination := t.TempDir()
+			err = archive.Untar(reader, destination)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(filepath.Join(destination, "copy"))
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(string(contents)).To(Equal("world"))
+
+			_, err = volume.Export(context.Background(), "../escape")
+			assert.Expect(err).To(HaveOccurred())
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" volume size", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			if !client.Capabilities().VolumeSizeLimits {
+				t.Skip("volume sizes are not supported")
+			}
+
+			taskID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      taskID.String(),
+					Image:   "alpine",
+					Command: []string{"sh", "-c", "head -c 1048576 /dev/zero > ./test/big"},
+					Mounts: orchestra.Mounts{
+						{Name: "test", Path: "/test", Size: 4096},
+					},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer func(container orchestra.Container) { _ = container.Cleanup(context.Background()) }(container)
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone() && status.ExitCode() != 0
+			}, "10s").Should(BeTrue())
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" volume size cannot change", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			client, err := init(orchestra.Options{Namespace: "test"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			_, err = client.CreateVolume(context.Background(), "unsized", 0)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CreateVolume(context.Background(), "unsized", 0)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CreateVolume(context.Background(), "unsized", 4096)
+			assert.Expect(err).To(MatchError(orchestra.ErrVolumeSizeMismatch))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
 		t.Run(name+" volume", func(t *testing.T) {
 			assert := NewGomegaWithT(t)
 
-			client, err := init("test")
+			client, err := init(orchestra.Options{Namespace: "test"})
 			assert.Expect(err).NotTo(HaveOccurred())
 			defer client.Close()
 
@@ -191,5 +762,118 @@ func TestDrivers(t *testing.T) {
 			err = client.Close()
 			assert.Expect(err).NotTo(HaveOccurred())
 		})
+
+		t.Run(name+" namespaces do not collide", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			runID, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			client, err := init(orchestra.Options{Namespace: "test-" + runID.String() + "-a"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			other, err := init(orchestra.Options{Namespace: "test-" + runID.String() + "-b"})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer other.Close()
+
+			source := t.TempDir()
+			err = os.WriteFile(filepath.Join(source, "hello"), []byte("world"), 0o600)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			tarball := &bytes.Buffer{}
+			err = archive.Tar(source, tarball, nil)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			volume, err := client.CreateVolume(context.Background(), "shared", 0)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			err = volume.Import(context.Background(), "seed", tarball)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			// the same volume name in another run is a different volume, and closing that run leaves this one
+			_, err = other.CreateVolume(context.Background(), "shared", 0)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			err = other.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			reader, err := volume.Export(context.Background(), "seed")
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer reader.Close()
+
+			destination := t.TempDir()
+			err = archive.Untar(reader, destination)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(filepath.Join(destination, "hello"))
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(string(contents)).To(Equal("world"))
+
+			err = client.Close()
+			assert.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run(name+" collector", func(t *testing.T) {
+			assert := NewGomegaWithT(t)
+
+			namespace, err := uuid.NewV7()
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			client, err := init(orchestra.Options{Namespace: "test-" + namespace.String()})
+			assert.Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			collector, ok := client.(orchestra.Collector)
+			assert.Expect(ok).To(BeTrue())
+
+			container, err := client.RunContainer(
+				context.Background(),
+				orchestra.Task{
+					ID:      namespace.String(),
+					Image:   "alpine",
+					Command: []string{"echo", "hello"},
+					Mounts: orchestra.Mounts{
+						{Name: "test", Path: "/test"},
+					},
+				},
+			)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			assert.Eventually(func() bool {
+				status, err := container.Status(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				return status.IsDone()
+			}, "10s").Should(BeTrue())
+
+			owned := func() []orchestra.Resource {
+				resources, err := collector.Resources(context.Background())
+				assert.Expect(err).NotTo(HaveOccurred())
+
+				found := []orchestra.Resource{}
+
+				for _, resource := range resources {
+					if resource.Namespace == "test-"+namespace.String() {
+						found = append(found, resource)
+					}
+				}
+
+				return found
+			}
+
+			resources := owned()
+			assert.Expect(resources).NotTo(BeEmpty())
+
+			for _, resource := range resources {
+				assert.Expect(resource.Owner).To(Equal(orchestra.CurrentOwner()))
+				assert.Expect(resource.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+
+				err = collector.RemoveResource(context.Background(), resource)
+				assert.Expect(err).NotTo(HaveOccurred())
+			}
+
+			assert.Expect(owned()).To(BeEmpty())
+		})
 	})
 }
@@ -0,0 +1,103 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+const markerFilename = ".orchestra.json"
+
+// marker is written into the directory of every driver, so it can be found and collected later.
+type marker struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels"`
+	Namespace string            `json:"namespace"`
+	Owner     string            `json:"owner"`
+}
+
+func writeMarker(path string, options orchestra.Options) error {
+	contents, err := json.Marshal(marker{
+		CreatedAt: time.Now(),
+		Labels:    options.Labels,
+		Namespace: options.Namespace,
+		Owner:     orchestra.CurrentOwner().String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal marker: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(path, markerFilename), contents, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write marker: %w", err)
+	}
+
+	return nil
+}
+
+// Resources implements orchestra.Collector.
+func (n *Native) Resources(ctx context.Context) ([]orchestra.Resource, error) {
+	entries, err := os.ReadDir(n.base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base dir: %w", err)
+	}
+
+	resources := []orchestra.Resource{}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(n.base, entry.Name())
+
+		contents, err := os.ReadFile(filepath.Join(path, markerFilename))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read marker: %w", err)
+		}
+
+		var found marker
+
+		err = json.Unmarshal(contents, &found)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal marker %s: %w", path, err)
+		}
+
+		owner, _ := orchestra.ParseOwner(found.Owner)
+
+		resources = append(resources, orchestra.Resource{
+			CreatedAt: found.CreatedAt,
+			ID:        path,
+			Kind:      "directory",
+			Labels:    found.Labels,
+			Namespace: found.Namespace,
+			Owner:     owner,
+		})
+	}
+
+	return resources, nil
+}
+
+// RemoveResource implements orchestra.Collector.
+func (n *Native) RemoveResource(ctx context.Context, resource orchestra.Resource) error {
+	if filepath.Dir(resource.ID) != n.base {
+		return fmt.Errorf("%w: %s", ErrInvalidPath, resource.ID)
+	}
+
+	err := removeAll(resource.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove directory %s: %w", resource.ID, err)
+	}
+
+	return nil
+}
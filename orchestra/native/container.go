@@ -7,22 +7,137 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jtarchie/ci/orchestra"
 )
 
 type NativeContainer struct {
-	command *exec.Cmd
-	stdout  *strings.Builder
-	errChan chan error
+	command    *exec.Cmd
+	dir        string
+	done       chan struct{}
+	driver     *Native
+	env        []string
+	errChan    chan error
+	exceeded   atomic.Pointer[NativeVolume]
+	finishedAt time.Time
+	ready      atomic.Bool
+	sandbox    *sandbox
+	startedAt  atomic.Pointer[time.Time]
+	stdout     *strings.Builder
+	task       orchestra.Task
+	timedOut   atomic.Bool
+	volumes    []*NativeVolume
 }
 
+// Cleanup implements orchestra.Container.
+// It kills the processes of the task, including those in the background, and removes its directory.
 func (n *NativeContainer) Cleanup(ctx context.Context) error {
+	err := n.kill(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = removeAll(n.dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove task dir: %w", err)
+	}
+
+	err = os.Remove(taskStatePath(n.dir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove task state: %w", err)
+	}
+
+	n.driver.forget(n)
+
 	return nil
 }
 
+// kill kills the process group of the task, and waits for the command to be done.
+func (n *NativeContainer) kill(ctx context.Context) error {
+	if n.started().IsZero() {
+		return nil
+	}
+
+	// the group was killed when the command exited, since then its pid can belong to another process
+	select {
+	case <-n.done:
+		return nil
+	default:
+	}
+
+	err := killProcessGroup(n.command)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for command: %w", ctx.Err())
+	}
+}
+
+var (
+	ErrContainerNotRunning = errors.New("container is not running")
+	ErrEmptyCommand        = errors.New("command is empty")
+)
+
+// Exec implements orchestra.Container.
+// The command runs as a sibling of the task, in its directory and with its environment.
+// In a sandbox, it has a sandbox of its own with the same mounts, but not the processes or network of the task.
+func (n *NativeContainer) Exec(
+	ctx context.Context,
+	command []string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+) (int, error) {
+	if len(command) == 0 {
+		return 0, ErrEmptyCommand
+	}
+
+	select {
+	case <-n.done:
+		return 0, ErrContainerNotRunning
+	default:
+	}
+
+	sibling, err := newCommand(ctx, n.dir, command, n.env, n.sandbox)
+	if err != nil {
+		return 0, err
+	}
+
+	sibling.Stdin = stdin
+	sibling.Stdout = stdout
+	sibling.Stderr = stderr
+
+	err = sibling.Start()
+	if err == nil {
+		err = waitCommand(sibling)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec: %w", err)
+	}
+
+	return 0, nil
+}
+
+// ID implements orchestra.Container.
+// It is the directory the command runs in.
+func (n *NativeContainer) ID() string {
+	return n.dir
+}
+
 func (n *NativeContainer) Logs(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
 	_, err := io.WriteString(stdout, n.stdout.String())
 	if err != nil {
@@ -33,8 +148,12 @@ func (n *NativeContainer) Logs(ctx context.Context, stdout io.Writer, stderr io.
 }
 
 type NativeStatus struct {
-	exitCode int
-	isDone   bool
+	exitCode   int
+	finishedAt time.Time
+	isReady    bool
+	reason     string
+	startedAt  time.Time
+	state      orchestra.State
 }
 
 func (n *NativeStatus) ExitCode() int {
@@ -42,7 +161,33 @@ func (n *NativeStatus) ExitCode() int {
 }
 
 func (n *NativeStatus) IsDone() bool {
-	return n.isDone
+	return n.state.IsDone()
+}
+
+func (n *NativeStatus) State() orchestra.State {
+	return n.state
+}
+
+func (n *NativeStatus) Reason() string {
+	return n.reason
+}
+
+func (n *NativeStatus) StartedAt() time.Time {
+	return n.startedAt
+}
+
+func (n *NativeStatus) FinishedAt() time.Time {
+	return n.finishedAt
+}
+
+func (n *NativeStatus) IsReady() bool {
+	return n.isReady
+}
+
+// IsOOMKilled implements orchestra.ContainerStatus.
+// Going over an rlimit fails allocations instead of killing the process, so it cannot be detected.
+func (n *NativeStatus) IsOOMKilled() bool {
+	return false
 }
 
 func (n *NativeContainer) Status(ctx context.Context) (orchestra.ContainerStatus, error) {
@@ -50,29 +195,92 @@ func (n *NativeContainer) Status(ctx context.Context) (orchestra.ContainerStatus
 	case <-ctx.Done():
 		return nil, fmt.Errorf("failed to get status: %w", context.Canceled)
 	case err := <-n.errChan:
-		if err != nil {
-			var exitErr *exec.ExitError
+		defer func() { n.errChan <- err }()
 
-			if !errors.As(err, &exitErr) {
-				return nil, fmt.Errorf("failed to get status: %w", err)
-			}
+		status := &NativeStatus{
+			exitCode:   n.command.ProcessState.ExitCode(),
+			finishedAt: n.finishedAt,
+			startedAt:  n.started(),
 		}
 
-		defer func() { n.errChan <- err }()
+		var exitErr *exec.ExitError
+
+		switch {
+		case n.timedOut.Load():
+			status.state = orchestra.StateTimedOut
+			status.reason = "killed after timeout"
+		case n.exceeded.Load() != nil:
+			volume := n.exceeded.Load()
+			status.exitCode = 1
+			status.state = orchestra.StateFailed
+			status.reason = fmt.Sprintf("volume %q exceeded its size of %d bytes", volume.name, volume.size)
+		case err == nil:
+			status.state = orchestra.StateSucceeded
+		case errors.As(err, &exitErr):
+			status.state = orchestra.StateFailed
+			status.reason = exitErr.Error()
+		default:
+			status.state = orchestra.StateErrored
+			status.reason = err.Error()
+		}
+
+		return status, nil
+	default:
+		status := &NativeStatus{
+			exitCode:  -1,
+			isReady:   n.task.Readiness == nil || n.ready.Load(),
+			startedAt: n.started(),
+			state:     orchestra.StateRunning,
+		}
+
+		if status.startedAt.IsZero() {
+			status.isReady = false
+			status.state = orchestra.StatePending
+		}
+
+		return status, nil
+	}
+}
 
-		return &NativeStatus{
-			exitCode: n.command.ProcessState.ExitCode(),
-			isDone:   n.command.ProcessState.Exited(),
-		}, nil
+// Usage implements orchestra.UsageReporter.
+// It is only known once the command has exited, until then it is empty.
+func (n *NativeContainer) Usage(ctx context.Context) (orchestra.Usage, error) {
+	select {
+	case <-n.done:
 	default:
-		return &NativeStatus{
-			exitCode: -1,
-			isDone:   false,
-		}, nil
+		return orchestra.Usage{}, nil
+	}
+
+	if n.command.ProcessState == nil {
+		return orchestra.Usage{}, nil
+	}
+
+	return processUsage(n.command.ProcessState), nil
+}
+
+func (n *NativeContainer) started() time.Time {
+	if startedAt := n.startedAt.Load(); startedAt != nil {
+		return *startedAt
 	}
+
+	return time.Time{}
 }
 
+// RunContainer implements orchestra.Driver.
+// Like docker, a task that has already been run, and not cleaned up, returns the same container.
 func (n *Native) RunContainer(ctx context.Context, task orchestra.Task) (orchestra.Container, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if container, ok := n.containers[task.ID]; ok {
+		return container, nil
+	}
+
+	// rlimits have no equivalent of a cpu limit
+	if task.Resources.CPU > 0 {
+		return nil, fmt.Errorf("cpu limits are %w", orchestra.ErrUnsupportedCapability)
+	}
+
 	containerName := fmt.Sprintf("%s-%s", n.namespace, task.ID)
 
 	dir, err := os.MkdirTemp(n.path, containerName)
@@ -80,34 +288,144 @@ func (n *Native) RunContainer(ctx context.Context, task orchestra.Task) (orchest
 		return nil, fmt.Errorf("failed to create temp dir: %w", err)
 	}
 
+	// a task that does not start leaves nothing behind, as it is not tracked to be cleaned up
+	discard := func() {
+		_ = removeAll(dir)
+		_ = os.Remove(taskStatePath(dir))
+	}
+
+	volumes := []*NativeVolume{}
+
 	for _, mount := range task.Mounts {
-		volume, err := n.CreateVolume(ctx, mount.Name, 0)
+		volume, err := n.mount(ctx, dir, mount)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create volume: %w", err)
-		}
+			discard()
 
-		nativeVolume, _ := volume.(*NativeVolume)
+			return nil, err
+		}
 
-		err = os.Symlink(nativeVolume.path, filepath.Join(dir, mount.Name))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create symlink: %w", err)
+		if volume != nil {
+			volumes = append(volumes, volume)
 		}
 	}
 
 	errChan := make(chan error, 1)
 
-	//nolint:gosec
-	command := exec.CommandContext(ctx, task.Command[0], task.Command[1:]...)
+	env := []string{}
+	for name, value := range task.Env {
+		env = append(env, name+"="+value)
+	}
+
+	sandbox := n.sandboxFor(dir, task)
+
+	err = writeTaskState(dir, taskState{Env: env, Sandbox: sandbox})
+	if err != nil {
+		discard()
+
+		return nil, err
+	}
+
+	command, err := newCommand(ctx, dir, task.Command, env, sandbox)
+	if err != nil {
+		discard()
+
+		return nil, err
+	}
+
+	// a sandbox applies the limits itself
+	if sandbox == nil && !task.Resources.IsZero() {
+		err = limitCommand(command, task.Resources)
+		if err != nil {
+			discard()
+
+			return nil, err
+		}
+	}
+
+	// the output is a pipe, rather than a writer, so waiting for the command
+	// does not also wait for the processes it left in the background
+	output, writer, err := os.Pipe()
+	if err != nil {
+		discard()
 
-	command.Dir = dir
-	command.Env = []string{}
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
 
 	stdout := &strings.Builder{}
-	command.Stderr = stdout
-	command.Stdout = stdout
+	command.Stderr = writer
+	command.Stdin = task.Stdin
+	command.Stdout = writer
+
+	container := &NativeContainer{
+		command: command,
+		dir:     dir,
+		driver:  n,
+		done:    make(chan struct{}),
+		env:     env,
+		errChan: errChan,
+		sandbox: sandbox,
+		stdout:  stdout,
+		task:    task,
+		volumes: volumes,
+	}
+
+	if n.containers == nil {
+		n.containers = map[string]*NativeContainer{}
+	}
+
+	// tracked before it starts, so its processes are killed when the driver is closed
+	n.containers[task.ID] = container
+
+	err = command.Start()
+	_ = writer.Close()
+
+	if err != nil {
+		_ = output.Close()
+		container.finishedAt = time.Now()
+		errChan <- fmt.Errorf("failed to start command: %w", err)
+		close(container.done)
+
+		return container, nil
+	}
+
+	startedAt := time.Now()
+	container.startedAt.Store(&startedAt)
+
+	copied := make(chan struct{})
+
+	go func() {
+		defer close(copied)
+		defer output.Close()
+
+		_, _ = io.Copy(stdout, output)
+	}()
 
 	go func() {
-		err := command.Run()
+		defer close(container.done)
+
+		if task.Timeout > 0 {
+			timer := time.AfterFunc(task.Timeout, func() {
+				// killing fails when the command has already exited, and it did not time out
+				if command.Process.Kill() == nil {
+					container.timedOut.Store(true)
+				}
+			})
+			defer timer.Stop()
+		}
+
+		go container.watchVolumes()
+
+		// nothing the task started outlives it
+		err := waitCommand(command)
+		container.finishedAt = time.Now()
+		<-copied
+
+		container.checkVolumes()
+
+		if volume := container.exceeded.Load(); volume != nil {
+			fmt.Fprintf(stdout, "volume %q exceeded its size of %d bytes\n", volume.name, volume.size)
+		}
+
 		if err != nil {
 			errChan <- fmt.Errorf("failed to run command: %w", err)
 
@@ -117,9 +435,49 @@ func (n *Native) RunContainer(ctx context.Context, task orchestra.Task) (orchest
 		errChan <- nil
 	}()
 
-	return &NativeContainer{
-		command: command,
-		errChan: errChan,
-		stdout:  stdout,
-	}, nil
+	if task.Readiness != nil {
+		go container.watchReadiness(*task.Readiness)
+	}
+
+	return container, nil
+}
+
+const volumeCheckInterval = 250 * time.Millisecond
+
+// watchVolumes enforces the size of volumes as a best effort, by checking their usage every interval
+// while the command runs, and killing the command once one has been exceeded.
+// Nothing stops a write past the size, it is only found afterwards.
+func (n *NativeContainer) watchVolumes() {
+	if !slices.ContainsFunc(n.volumes, func(volume *NativeVolume) bool { return volume.size > 0 }) {
+		return
+	}
+
+	ticker := time.NewTicker(volumeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+		}
+
+		if n.checkVolumes() {
+			_ = n.command.Process.Kill()
+
+			return
+		}
+	}
+}
+
+func (n *NativeContainer) checkVolumes() bool {
+	for _, volume := range n.volumes {
+		if volume.isExceeded() {
+			n.exceeded.CompareAndSwap(nil, volume)
+
+			return true
+		}
+	}
+
+	return false
 }
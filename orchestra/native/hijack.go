@@ -0,0 +1,102 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// taskState is what a shell needs to run like the task, it is written next to the directory of the task,
+// as a hijacking driver only has the directory.
+type taskState struct {
+	Env     []string `json:"env"`
+	Sandbox *sandbox `json:"sandbox"`
+}
+
+func taskStatePath(dir string) string {
+	return dir + ".json"
+}
+
+func writeTaskState(dir string, state taskState) error {
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task state: %w", err)
+	}
+
+	err = os.WriteFile(taskStatePath(dir), contents, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write task state: %w", err)
+	}
+
+	return nil
+}
+
+func readTaskState(dir string) (taskState, error) {
+	var state taskState
+
+	contents, err := os.ReadFile(taskStatePath(dir))
+	if err != nil {
+		return state, fmt.Errorf("failed to read task state: %w", err)
+	}
+
+	err = json.Unmarshal(contents, &state)
+	if err != nil {
+		return state, fmt.Errorf("failed to unmarshal task state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Hijack implements orchestra.Hijacker.
+// It starts a shell in the directory of the task, with the environment of the task.
+// A sandboxed task gets a shell in the same sandbox, with its mounts, root, and limits.
+func (n *Native) Hijack(ctx context.Context, containerID string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	// task directories are in the directory of a driver, which is in the base directory
+	if filepath.Dir(filepath.Dir(containerID)) != n.base {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidPath, containerID)
+	}
+
+	state, err := readTaskState(containerID)
+	if err != nil {
+		return 0, err
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" || state.Sandbox != nil {
+		shell = "sh"
+	}
+
+	command, err := newCommand(ctx, containerID, []string{shell}, state.Env, state.Sandbox)
+	if err != nil {
+		return 0, err
+	}
+
+	// a shell in a group of its own is in the background of a terminal, and is stopped when it reads,
+	// so it only kills itself, a sandbox takes the rest of its namespace with it
+	shareProcessGroup(command)
+
+	command.Cancel = func() error {
+		return command.Process.Kill()
+	}
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	err = command.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to run shell: %w", err)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,124 @@
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/jtarchie/ci/orchestra"
+	"golang.org/x/sys/unix"
+)
+
+const supportsResourceLimits = true
+
+// limitsEnv has the limits of a re-executed driver.
+const limitsEnv = "ORCHESTRA_LIMITS"
+
+// limits are applied by the re-executed driver before it replaces itself with the command,
+// so the command and everything it forks are started within them.
+type limits struct {
+	Command []string `json:"command"`
+	// Path is used to find the command, when the environment has none.
+	Path      string              `json:"path"`
+	Resources orchestra.Resources `json:"resources"`
+}
+
+// limitCommand changes the command to run through the re-executed driver.
+func limitCommand(command *exec.Cmd, resources orchestra.Resources) error {
+	config, err := json.Marshal(limits{
+		Command:   command.Args,
+		Path:      os.Getenv("PATH"),
+		Resources: resources,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+
+	command.Path = "/proc/self/exe"
+	command.Args = []string{"orchestra-limits"}
+	command.Env = append(slices.Clone(command.Env), limitsEnv+"="+string(config))
+	// the command is looked up by the re-executed driver instead
+	command.Err = nil
+
+	return nil
+}
+
+func init() {
+	config, ok := os.LookupEnv(limitsEnv)
+	if !ok {
+		return
+	}
+
+	var limits limits
+
+	err := json.Unmarshal([]byte(config), &limits)
+	if err == nil {
+		err = limits.exec()
+	}
+
+	fmt.Fprintf(os.Stderr, "limits: %s\n", err)
+
+	if errors.Is(err, exec.ErrNotFound) {
+		os.Exit(127)
+	}
+
+	os.Exit(126)
+}
+
+// exec replaces the process with the command, within the limits.
+func (l *limits) exec() error {
+	env := slices.DeleteFunc(os.Environ(), func(value string) bool {
+		return strings.HasPrefix(value, limitsEnv+"=")
+	})
+
+	if os.Getenv("PATH") == "" {
+		_ = os.Setenv("PATH", l.Path)
+	}
+
+	path, err := exec.LookPath(l.Command[0])
+	if err != nil {
+		return fmt.Errorf("failed to find command: %w", err)
+	}
+
+	err = setResourceLimits(l.Resources)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Exec(path, l.Command, env)
+
+	return fmt.Errorf("failed to exec %s: %w", path, err)
+}
+
+// setResourceLimits sets the rlimits of the current process, which a command it execs keeps.
+// Memory limits the address space. Pids is RLIMIT_NPROC, which counts every process of the user,
+// not only the task's. CPU cannot be expressed as an rlimit, so it is rejected.
+func setResourceLimits(resources orchestra.Resources) error {
+	if resources.CPU > 0 {
+		return fmt.Errorf("cpu limits are %w", orchestra.ErrUnsupportedCapability)
+	}
+
+	if resources.Memory > 0 {
+		limit := uint64(resources.Memory)
+
+		err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: limit, Max: limit})
+		if err != nil {
+			return fmt.Errorf("failed to limit memory: %w", err)
+		}
+	}
+
+	if resources.Pids > 0 {
+		limit := uint64(resources.Pids)
+
+		err := unix.Setrlimit(unix.RLIMIT_NPROC, &unix.Rlimit{Cur: limit, Max: limit})
+		if err != nil {
+			return fmt.Errorf("failed to limit pids: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package native
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+const supportsResourceLimits = false
+
+func limitCommand(_ *exec.Cmd, resources orchestra.Resources) error {
+	if resources.IsZero() {
+		return nil
+	}
+
+	return fmt.Errorf("resource limits are %w", orchestra.ErrUnsupportedCapability)
+}
@@ -0,0 +1,118 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
+)
+
+// mount makes a mount available in the directory of a task, under its name.
+// Read-only mounts are copies without write permissions, so the task cannot change the original.
+// The volume is returned when its size has to be enforced.
+func (n *Native) mount(ctx context.Context, dir string, mount orchestra.Mount) (*NativeVolume, error) {
+	target := filepath.Join(dir, mount.Name)
+
+	switch mount.Kind() {
+	case orchestra.MountTypeTmpfs:
+		volume := &NativeVolume{
+			name: mount.Name,
+			path: target,
+			size: mount.Size,
+		}
+
+		err := os.MkdirAll(target, os.ModePerm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scratch dir: %w", err)
+		}
+
+		if mount.ReadOnly {
+			return volume, makeReadOnly(target)
+		}
+
+		return volume, nil
+	case orchestra.MountTypeHost:
+		if !mount.ReadOnly {
+			err := os.Symlink(mount.HostPath, target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create symlink: %w", err)
+			}
+
+			return nil, nil //nolint:nilnil
+		}
+
+		// unfiltered, so the task sees the same files as through a read-write mount
+		err := archive.Copy(mount.HostPath, target, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy host path: %w", err)
+		}
+
+		return nil, makeReadOnly(target)
+	case orchestra.MountTypeVolume:
+	}
+
+	volume, err := n.CreateVolume(ctx, mount.Name, mount.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume: %w", err)
+	}
+
+	nativeVolume, _ := volume.(*NativeVolume)
+
+	if mount.ReadOnly {
+		err = archive.Copy(nativeVolume.path, target, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy volume: %w", err)
+		}
+
+		return nil, makeReadOnly(target)
+	}
+
+	err = os.Symlink(nativeVolume.path, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create symlink: %w", err)
+	}
+
+	return nativeVolume, nil
+}
+
+// makeReadOnly removes the write permissions of everything in path.
+func makeReadOnly(path string) error {
+	err := filepath.WalkDir(path, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat: %w", err)
+		}
+
+		return os.Chmod(current, info.Mode().Perm()&^0o222)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to make read-only: %w", err)
+	}
+
+	return nil
+}
+
+// removeAll removes path, including the read-only directories of mounts.
+func removeAll(path string) error {
+	_ = filepath.WalkDir(path, func(current string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			_ = os.Chmod(current, 0o700)
+		}
+
+		return nil
+	})
+
+	return os.RemoveAll(path) //nolint:wrapcheck
+}
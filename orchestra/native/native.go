@@ -1,20 +1,57 @@
 package native
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 
 	"github.com/jtarchie/ci/orchestra"
 )
 
 type Native struct {
-	namespace string
-	path      string
+	base         string
+	ignoreImages bool
+	keep         bool
+	namespace    string
+	network      bool
+	path         string
+	sandbox      bool
+
+	containers map[string]*NativeContainer
+	mutex      sync.Mutex
+
+	volumes      map[string]*NativeVolume
+	volumesMutex sync.Mutex
 }
 
 // Close implements orchestra.Driver.
+// The processes of every task are killed, even when its directory is kept.
 func (n *Native) Close() error {
-	err := os.RemoveAll(n.path)
+	n.mutex.Lock()
+	containers := n.containers
+	n.containers = nil
+	n.mutex.Unlock()
+
+	errs := []error{}
+	for _, container := range containers {
+		errs = append(errs, container.kill(context.Background()))
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return err
+	}
+
+	if n.keep {
+		return nil
+	}
+
+	err = removeAll(n.path)
 	if err != nil {
 		return fmt.Errorf("failed to remove temp dir: %w", err)
 	}
@@ -22,29 +59,140 @@ func (n *Native) Close() error {
 	return nil
 }
 
-func NewNative(namespace string) (orchestra.Driver, error) {
-	path, err := os.MkdirTemp("", namespace)
+// NewNative creates a driver that runs commands in a directory under the endpoint,
+// otherwise the system temp directory is used.
+func NewNative(options orchestra.Options) (orchestra.Driver, error) {
+	return newNative(options, false)
+}
+
+// The `ignore-images` param runs tasks that have an image on the host anyway, otherwise they are rejected.
+// Native ignores them unless it is false, so pipelines written for docker still run,
+// a sandbox only when it is true, as it has images of its own.
+func newNative(options orchestra.Options, sandbox bool) (*Native, error) {
+	network, err := boolParam(options.Params, "network", !sandbox)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreImages, err := boolParam(options.Params, "ignore-images", !sandbox)
+	if err != nil {
+		return nil, err
+	}
+
+	base := options.Endpoint
+	if base == "" {
+		base = os.TempDir()
+	}
+
+	base, err = filepath.Abs(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	err = os.MkdirAll(base, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base dir: %w", err)
+	}
+
+	if options.Attach {
+		return &Native{
+			base:         base,
+			ignoreImages: ignoreImages,
+			keep:         true,
+			namespace:    options.Namespace,
+			network:      network,
+			sandbox:      sandbox,
+		}, nil
+	}
+
+	path, err := os.MkdirTemp(base, options.Namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp dir: %w", err)
 	}
 
+	err = writeMarker(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark temp dir: %w", err)
+	}
+
 	return &Native{
-		namespace: namespace,
-		path:      path,
+		base:         base,
+		ignoreImages: ignoreImages,
+		keep:         options.Keep,
+		namespace:    options.Namespace,
+		network:      network,
+		path:         path,
+		sandbox:      sandbox,
 	}, nil
 }
 
+// Capabilities implements orchestra.Driver.
+// Commands run directly on the host, so tasks with an image are only run when images are ignored.
+// A sandbox has images of extracted root filesystems, and services only with a network,
+// as their probes are made from the host. Neither has privileged tasks or a TTY.
+func (n *Native) Capabilities() orchestra.Capabilities {
+	capabilities := orchestra.Capabilities{
+		IgnoreImages:     n.ignoreImages,
+		Networking:       n.network,
+		ResourceLimits:   supportsResourceLimits,
+		Services:         n.network,
+		Stdin:            true,
+		Volumes:          true,
+		VolumeSizeLimits: true,
+	}
+
+	if n.sandbox {
+		capabilities.Images = true
+		capabilities.ImagePrefixes = []string{rootfsPrefix}
+	}
+
+	return capabilities
+}
+
+// forget stops tracking a container that was cleaned up, so its task can be run again.
+func (n *Native) forget(container *NativeContainer) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.containers[container.task.ID] == container {
+		delete(n.containers, container.task.ID)
+	}
+}
+
 func (n *Native) Name() string {
+	if n.sandbox {
+		return "sandbox"
+	}
+
 	return "native"
 }
 
+// boolParam parses a boolean param, the fallback is used when it is not set.
+func boolParam(params url.Values, name string, fallback bool) (bool, error) {
+	if !params.Has(name) {
+		return fallback, nil
+	}
+
+	value, err := strconv.ParseBool(params.Get(name))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return value, nil
+}
+
 func init() {
 	orchestra.Add("native", NewNative)
 }
 
 var (
-	_ orchestra.Driver          = &Native{}
-	_ orchestra.Container       = &NativeContainer{}
-	_ orchestra.ContainerStatus = &NativeStatus{}
-	_ orchestra.Volume          = &NativeVolume{}
+	_ orchestra.Collector        = &Native{}
+	_ orchestra.Driver           = &Native{}
+	_ orchestra.Hijacker         = &Native{}
+	_ orchestra.ServiceDriver    = &Native{}
+	_ orchestra.Container        = &NativeContainer{}
+	_ orchestra.ContainerStatus  = &NativeStatus{}
+	_ orchestra.UsageReporter    = &NativeContainer{}
+	_ orchestra.ServiceContainer = &NativeService{}
+	_ orchestra.Volume           = &NativeVolume{}
 )
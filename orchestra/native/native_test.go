@@ -0,0 +1,347 @@
+package native_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/native"
+	. "github.com/onsi/gomega"
+)
+
+// runTask runs the command to completion, returning its status and output.
+func runTask(assert *WithT, client orchestra.Driver, task orchestra.Task) (orchestra.ContainerStatus, string) {
+	taskID, err := uuid.NewV7()
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	task.ID = taskID.String()
+
+	container, err := client.RunContainer(context.Background(), task)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	var status orchestra.ContainerStatus
+
+	assert.Eventually(func() bool {
+		status, err = container.Status(context.Background())
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		return status.IsDone()
+	}, "10s").Should(BeTrue())
+
+	stdout, stderr := &strings.Builder{}, &strings.Builder{}
+	err = container.Logs(context.Background(), stdout, stderr)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	return status, stdout.String()
+}
+
+func TestNativeResourceLimits(t *testing.T) {
+	t.Parallel()
+
+	client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+	NewGomegaWithT(t).Expect(err).NotTo(HaveOccurred())
+
+	_ = client.Close()
+
+	if !client.Capabilities().ResourceLimits {
+		t.Skip("resource limits are not supported")
+	}
+
+	t.Run("forked processes are limited", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command:   []string{"sh", "-c", "sh -c 'ulimit -v' & wait"},
+			Resources: orchestra.Resources{Memory: 64 * 1024 * 1024},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(strings.TrimSpace(stdout)).To(Equal("65536"))
+	})
+
+	t.Run("cpu limits are rejected before the task starts", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		_, err = client.RunContainer(context.Background(), orchestra.Task{
+			ID:        "cpu",
+			Command:   []string{"touch", "started"},
+			Resources: orchestra.Resources{CPU: 1},
+		})
+		assert.Expect(err).To(MatchError(orchestra.ErrUnsupportedCapability))
+	})
+
+	t.Run("a missing command fails", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		status, _ := runTask(assert, client, orchestra.Task{
+			Command:   []string{"does-not-exist"},
+			Resources: orchestra.Resources{Memory: 64 * 1024 * 1024},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(127))
+	})
+}
+
+// isRunning is true while the process exists, and has not exited as a zombie waiting to be reaped.
+func isRunning(assert *WithT, pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	// the state follows the command name, which is in parentheses
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+
+	return fields[0] != "Z"
+}
+
+func TestNativeCleanup(t *testing.T) {
+	t.Parallel()
+
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("processes cannot be inspected without /proc")
+	}
+
+	t.Run("kills background processes and removes the task dir", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		container, err := client.RunContainer(context.Background(), orchestra.Task{
+			ID:      "background",
+			Command: []string{"sh", "-c", "sleep 60 & echo $!"},
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		assert.Eventually(func() bool {
+			status, err := container.Status(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			return status.IsDone()
+		}, "3s").Should(BeTrue())
+
+		stdout := &strings.Builder{}
+		assert.Expect(container.Logs(context.Background(), stdout, &strings.Builder{})).To(Succeed())
+
+		pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		// the group is killed once the command exits
+		assert.Eventually(func() bool { return isRunning(assert, pid) }, "3s").Should(BeFalse())
+
+		dir := container.ID()
+		assert.Expect(dir).To(BeADirectory())
+
+		assert.Expect(container.Cleanup(context.Background())).To(Succeed())
+		assert.Expect(isRunning(assert, pid)).To(BeFalse())
+		assert.Expect(dir).NotTo(BeADirectory())
+	})
+
+	t.Run("kills what an exec leaves in the background", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		container, err := client.RunContainer(context.Background(), orchestra.Task{
+			ID:      "exec",
+			Command: []string{"sleep", "60"},
+		})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = container.Cleanup(context.Background()) }()
+
+		stdout := &strings.Builder{}
+		code, err := container.Exec(context.Background(), []string{"sh", "-c", "sleep 60 & echo $!"}, nil, stdout, &strings.Builder{})
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(code).To(Equal(0))
+
+		pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		assert.Eventually(func() bool { return isRunning(assert, pid) }, "3s").Should(BeFalse())
+	})
+
+	t.Run("removes the task dir when the task cannot start", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		endpoint := t.TempDir()
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: endpoint, Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		_, err = client.RunContainer(context.Background(), orchestra.Task{
+			ID:      "missing",
+			Command: []string{"true"},
+			Mounts: orchestra.Mounts{
+				{Name: "input", Path: "input", HostPath: filepath.Join(endpoint, "missing"), ReadOnly: true},
+			},
+		})
+		assert.Expect(err).To(HaveOccurred())
+
+		dirs, err := filepath.Glob(filepath.Join(endpoint, "test*", "test-missing*"))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(dirs).To(BeEmpty())
+	})
+
+	t.Run("removes the volume dir", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		endpoint := t.TempDir()
+
+		client, err := native.NewNative(orchestra.Options{Endpoint: endpoint, Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		status, _ := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", "echo data > data/file"},
+			Mounts:  orchestra.Mounts{{Name: "data", Path: "data"}},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+
+		// volumes are in the directory of the driver
+		dirs, err := filepath.Glob(filepath.Join(endpoint, "test*", "data"))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(dirs).To(HaveLen(1))
+		assert.Expect(filepath.Join(dirs[0], "file")).To(BeAnExistingFile())
+
+		volume, err := client.CreateVolume(context.Background(), "data", 0)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		assert.Expect(volume.Cleanup(context.Background())).To(Succeed())
+		assert.Expect(dirs[0]).NotTo(BeADirectory())
+	})
+}
+
+func TestNativeImages(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	task := orchestra.Task{Image: "alpine", Command: []string{"true"}}
+
+	// pipelines written for docker run on the host by default
+	client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+	assert.Expect(err).NotTo(HaveOccurred())
+	defer client.Close()
+
+	assert.Expect(client.Capabilities().Validate(task)).To(Succeed())
+	assert.Expect(client.Capabilities().SupportsImage(task.Image)).To(BeFalse())
+
+	client, err = native.NewNative(orchestra.Options{
+		Endpoint:  t.TempDir(),
+		Namespace: "test",
+		Params:    url.Values{"ignore-images": {"false"}},
+	})
+	assert.Expect(err).NotTo(HaveOccurred())
+	defer client.Close()
+
+	assert.Expect(client.Capabilities().Validate(task)).To(MatchError(orchestra.ErrUnsupportedCapability))
+}
+
+func TestNativeHostMounts(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	source := t.TempDir()
+	err := os.WriteFile(filepath.Join(source, ".gitignore"), []byte("ignored\n"), 0o600)
+	assert.Expect(err).NotTo(HaveOccurred())
+	err = os.WriteFile(filepath.Join(source, "ignored"), []byte("still there\n"), 0o600)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+	assert.Expect(err).NotTo(HaveOccurred())
+	defer client.Close()
+
+	status, stdout := runTask(assert, client, orchestra.Task{
+		Command: []string{"sh", "-c", "cat ./read-write/ignored ./read-only/ignored"},
+		Mounts: orchestra.Mounts{
+			{Name: "read-write", Path: "/read-write", HostPath: source},
+			{Name: "read-only", Path: "/read-only", HostPath: source, ReadOnly: true},
+		},
+	})
+	assert.Expect(status.ExitCode()).To(Equal(0))
+	assert.Expect(stdout).To(Equal("still there\nstill there\n"))
+}
+
+func TestNativeHijack(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	// the fifth field of the stat of a process is its group
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		t.Skip("process groups cannot be read")
+	}
+
+	client, err := native.NewNative(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test"})
+	assert.Expect(err).NotTo(HaveOccurred())
+	defer client.Close()
+
+	container, err := client.RunContainer(context.Background(), orchestra.Task{
+		ID:      "hijacked",
+		Command: []string{"true"},
+	})
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	assert.Eventually(func() bool {
+		status, err := container.Status(context.Background())
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		return status.IsDone()
+	}, "10s").Should(BeTrue())
+
+	hijacker, ok := client.(orchestra.Hijacker)
+	assert.Expect(ok).To(BeTrue())
+
+	// the shell stays in the foreground group of a terminal, like the driver
+	stdout := &strings.Builder{}
+	code, err := hijacker.Hijack(
+		context.Background(),
+		container.ID(),
+		strings.NewReader("cut -d' ' -f5 /proc/$$/stat\n"),
+		stdout, &strings.Builder{},
+	)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(code).To(Equal(0))
+	assert.Expect(strings.TrimSpace(stdout.String())).To(Equal(strings.Fields(string(stat))[4]))
+}
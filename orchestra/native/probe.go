@@ -0,0 +1,87 @@
+package native
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// watchReadiness runs the probe until it passes once, its retries run out, or the command exits.
+func (n *NativeContainer) watchReadiness(probe orchestra.Probe) {
+	probe = probe.WithDefaults()
+
+	ticker := time.NewTicker(probe.Interval)
+	defer ticker.Stop()
+
+	for failures := 0; failures < probe.Retries; failures++ {
+		select {
+		case <-n.done:
+			return
+		case <-ticker.C:
+		}
+
+		err := n.check(probe)
+		if err == nil {
+			n.ready.Store(true)
+
+			return
+		}
+	}
+}
+
+var ErrProbeFailed = errors.New("probe failed")
+
+func (n *NativeContainer) check(probe orchestra.Probe) error {
+	ctx, cancel := context.WithTimeout(context.Background(), probe.Timeout)
+	defer cancel()
+
+	switch {
+	case len(probe.Exec) > 0:
+		command, err := newCommand(ctx, n.dir, probe.Exec, n.env, n.sandbox)
+		if err != nil {
+			return err
+		}
+
+		err = command.Start()
+		if err == nil {
+			err = waitCommand(command)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to run probe: %w", err)
+		}
+	case probe.HTTPGet != nil:
+		url := "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(probe.HTTPGet.Port)) + probe.HTTPGet.Path
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create probe request: %w", err)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return fmt.Errorf("failed to request probe: %w", err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("%w: status code %d", ErrProbeFailed, response.StatusCode)
+		}
+	case probe.TCPPort > 0:
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(probe.TCPPort)))
+		if err != nil {
+			return fmt.Errorf("failed to connect probe: %w", err)
+		}
+		defer conn.Close()
+	}
+
+	return nil
+}
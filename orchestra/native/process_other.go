@@ -0,0 +1,28 @@
+//go:build !unix
+
+package native
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(_ *exec.Cmd) {}
+
+func shareProcessGroup(_ *exec.Cmd) {}
+
+// killProcessGroup only kills the command, as there are no process groups.
+func killProcessGroup(command *exec.Cmd) error {
+	if command.Process == nil {
+		return nil
+	}
+
+	err := command.Process.Kill()
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package native
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a process group of its own,
+// so the processes it leaves in the background can be killed with it.
+func setProcessGroup(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	command.SysProcAttr.Setpgid = true
+}
+
+// shareProcessGroup keeps the command in the process group of the driver,
+// which is the foreground group of a terminal, so a shell can read from it.
+func shareProcessGroup(command *exec.Cmd) {
+	if command.SysProcAttr != nil {
+		command.SysProcAttr.Setpgid = false
+	}
+}
+
+// killProcessGroup kills every process in the group of a started command,
+// it is not an error when they have all exited.
+func killProcessGroup(command *exec.Cmd) error {
+	if command.Process == nil {
+		return nil
+	}
+
+	err := syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to kill process group: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package native
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// sandbox isolates a command in linux namespaces.
+// It is passed to the re-executed driver, which sets up the mounts before running the command.
+type sandbox struct {
+	// Binds are directories of the host that are mounted read-write at the same path.
+	Binds   []string `json:"binds"`
+	Command []string `json:"command"`
+	Dir     string   `json:"dir"`
+	Network bool     `json:"network"`
+	// Path is used to find the command, when the environment has none.
+	Path string `json:"path"`
+	// Resources are applied just before the command runs, as the driver would not start within them.
+	Resources orchestra.Resources `json:"resources"`
+	// Root is an empty directory the new root is mounted on, only in the namespace of the command.
+	Root string `json:"root"`
+	// Rootfs is an extracted image, otherwise the system directories of the host are mounted read-only.
+	Rootfs string `json:"rootfs"`
+}
+
+// rootfsPrefix marks an image as an extracted root filesystem, rather than a registry reference.
+const rootfsPrefix = "rootfs:"
+
+// sandboxFor is the sandbox of a task in dir, nil when the driver does not sandbox.
+// Only the directory of the task is bound, and what its read-write mounts link to,
+// so it cannot reach the other tasks and volumes of the driver.
+func (n *Native) sandboxFor(dir string, task orchestra.Task) *sandbox {
+	if !n.sandbox {
+		return nil
+	}
+
+	config := &sandbox{
+		Binds:     []string{dir},
+		Network:   n.network,
+		Resources: task.Resources,
+		Root:      filepath.Join(filepath.Dir(dir), ".sandbox"),
+	}
+
+	// read-only mounts are copies in the directory of the task
+	for _, mount := range task.Mounts {
+		if mount.ReadOnly {
+			continue
+		}
+
+		switch mount.Kind() {
+		case orchestra.MountTypeHost:
+			config.Binds = append(config.Binds, mount.HostPath)
+		case orchestra.MountTypeVolume:
+			config.Binds = append(config.Binds, filepath.Join(n.path, mount.Name))
+		case orchestra.MountTypeTmpfs:
+		}
+	}
+
+	if rootfs, ok := strings.CutPrefix(task.Image, rootfsPrefix); ok {
+		config.Rootfs, _ = filepath.Abs(rootfs)
+	}
+
+	return config
+}
+
+// waitDelay is how long output is still read after a command was killed,
+// as a process left in the background can keep it open.
+const waitDelay = 5 * time.Second
+
+// newCommand creates the command to run in dir, isolated when there is a sandbox.
+// It runs in a process group of its own, which is killed when ctx is done.
+func newCommand(ctx context.Context, dir string, args []string, env []string, sandbox *sandbox) (*exec.Cmd, error) {
+	var command *exec.Cmd
+
+	if sandbox == nil {
+		//nolint:gosec
+		command = exec.CommandContext(ctx, args[0], args[1:]...)
+		command.Dir = dir
+		command.Env = env
+	} else {
+		var err error
+
+		command, err = sandbox.command(ctx, dir, args, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setProcessGroup(command)
+
+	command.Cancel = func() error {
+		return killProcessGroup(command)
+	}
+	command.WaitDelay = waitDelay
+
+	return command, nil
+}
+
+// waitCommand waits for a started command, and kills what it left in the background.
+// The group is killed before the command is reaped, as then its pid can belong to another process.
+func waitCommand(command *exec.Cmd) error {
+	err := waitExited(command)
+	if err == nil {
+		_ = killProcessGroup(command)
+	}
+
+	return command.Wait()
+}
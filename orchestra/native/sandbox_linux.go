@@ -0,0 +1,386 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/jtarchie/ci/orchestra"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv has the sandbox of a re-executed driver.
+const sandboxEnv = "ORCHESTRA_SANDBOX"
+
+// NewSandbox creates a native driver that runs every command in its own user, mount, pid,
+// and network namespaces. The command sees the system directories of the host read-only,
+// or the rootfs of an image like `rootfs:<path>`, and only the directories of its task.
+// It has no network, unless the `network` param is true.
+func NewSandbox(options orchestra.Options) (orchestra.Driver, error) {
+	return newNative(options, true)
+}
+
+// command re-executes the driver in new namespaces, which sets up the sandbox,
+// and then replaces itself with the command.
+func (s *sandbox) command(ctx context.Context, dir string, args []string, env []string) (*exec.Cmd, error) {
+	s.Command = args
+	s.Dir = dir
+	s.Path = os.Getenv("PATH")
+
+	err := os.MkdirAll(s.Root, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+
+	config, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sandbox: %w", err)
+	}
+
+	flags := unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC
+	if !s.Network {
+		flags |= unix.CLONE_NEWNET
+	}
+
+	command := exec.CommandContext(ctx, "/proc/self/exe")
+	command.Args = []string{"orchestra-sandbox"}
+	command.Env = append(slices.Clone(env), sandboxEnv+"="+string(config))
+	command.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: uintptr(flags),
+		// the command is root in its namespace, which is the current user outside of it
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+
+	return command, nil
+}
+
+func init() {
+	orchestra.Add("sandbox", NewSandbox)
+
+	config, ok := os.LookupEnv(sandboxEnv)
+	if !ok {
+		return
+	}
+
+	var sandbox sandbox
+
+	err := json.Unmarshal([]byte(config), &sandbox)
+	if err == nil {
+		err = sandbox.run()
+	}
+
+	fmt.Fprintf(os.Stderr, "sandbox: %s\n", err)
+
+	if errors.Is(err, exec.ErrNotFound) {
+		os.Exit(127)
+	}
+
+	os.Exit(126)
+}
+
+// hostDirs are the system directories of the host, that a sandbox without a rootfs sees.
+var hostDirs = []string{"/bin", "/etc", "/lib", "/lib32", "/lib64", "/libx32", "/sbin", "/usr"}
+
+// devices are bound from the host, as device nodes cannot be created in a user namespace.
+var devices = []string{"/dev/full", "/dev/null", "/dev/random", "/dev/tty", "/dev/urandom", "/dev/zero"}
+
+// run sets up the sandbox in the new namespaces, and only returns when it could not run the command.
+func (s *sandbox) run() error {
+	err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	err = unix.Mount("tmpfs", s.Root, "tmpfs", 0, "mode=755")
+	if err != nil {
+		return fmt.Errorf("failed to mount root: %w", err)
+	}
+
+	if s.Rootfs != "" {
+		err = s.bindRootfs()
+	} else {
+		err = s.bindHost()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = s.mountSystem()
+	if err != nil {
+		return err
+	}
+
+	for _, bind := range s.Binds {
+		err = s.bind(bind, bind, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !s.Network {
+		err = loopbackUp()
+		if err != nil {
+			return err
+		}
+	}
+
+	_ = unix.Sethostname([]byte("sandbox"))
+
+	err = s.pivot()
+	if err != nil {
+		return err
+	}
+
+	return s.exec()
+}
+
+// bindRootfs mounts every entry of the rootfs read-only, so the extracted image is not changed.
+func (s *sandbox) bindRootfs() error {
+	entries, err := os.ReadDir(s.Rootfs)
+	if err != nil {
+		return fmt.Errorf("failed to read rootfs: %w", err)
+	}
+
+	for _, entry := range entries {
+		err = s.bind(filepath.Join(s.Rootfs, entry.Name()), "/"+entry.Name(), true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sandbox) bindHost() error {
+	for _, dir := range hostDirs {
+		err := s.bind(dir, dir, true)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mountSystem mounts /proc of the pid namespace, the basic devices, and scratch space in /tmp.
+func (s *sandbox) mountSystem() error {
+	mounts := []struct {
+		fstype string
+		flags  uintptr
+		target string
+	}{
+		{"proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, "/proc"},
+		{"tmpfs", unix.MS_NOSUID, "/dev"},
+		{"tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "/dev/shm"},
+		{"tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "/tmp"},
+	}
+
+	for _, mount := range mounts {
+		target := filepath.Join(s.Root, mount.target)
+
+		err := os.MkdirAll(target, 0o755) //nolint:mnd
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", mount.target, err)
+		}
+
+		err = unix.Mount(mount.fstype, target, mount.fstype, mount.flags, "")
+		if err != nil {
+			return fmt.Errorf("failed to mount %s: %w", mount.target, err)
+		}
+	}
+
+	for _, device := range devices {
+		err := s.bind(device, device, false)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, target := range map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	} {
+		err := os.Symlink(target, filepath.Join(s.Root, "dev", name))
+		if err != nil {
+			return fmt.Errorf("failed to link /dev/%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// bind mounts source at target in the new root, symlinks are recreated rather than followed.
+func (s *sandbox) bind(source string, target string, readOnly bool) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", source, err)
+	}
+
+	target = filepath.Join(s.Root, target)
+
+	err = os.MkdirAll(filepath.Dir(target), 0o755) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return fmt.Errorf("failed to read link %s: %w", source, err)
+		}
+
+		err = os.Symlink(link, target)
+		if err != nil {
+			return fmt.Errorf("failed to link %s: %w", source, err)
+		}
+
+		return nil
+	case info.IsDir():
+		err = os.MkdirAll(target, 0o755) //nolint:mnd
+	default:
+		err = os.WriteFile(target, nil, 0o644) //nolint:mnd
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+
+	err = unix.Mount(source, target, "", unix.MS_BIND, "")
+	if err != nil {
+		return fmt.Errorf("failed to bind %s: %w", source, err)
+	}
+
+	if !readOnly {
+		return nil
+	}
+
+	// a remount in a user namespace has to keep the flags it cannot change
+	var stat unix.Statfs_t
+
+	err = unix.Statfs(source, &stat)
+	if err != nil {
+		return fmt.Errorf("failed to stat mount of %s: %w", source, err)
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+
+	for statFlag, mountFlag := range map[int64]uintptr{
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if stat.Flags&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+
+	err = unix.Mount("", target, "", flags, "")
+	if err != nil {
+		return fmt.Errorf("failed to make %s read-only: %w", source, err)
+	}
+
+	return nil
+}
+
+// loopbackUp brings up the loopback interface of the new network namespace,
+// so the command can still reach itself on localhost.
+func loopbackUp() error {
+	socket, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open socket: %w", err)
+	}
+	defer unix.Close(socket)
+
+	ifreq, err := unix.NewIfreq("lo")
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	err = unix.IoctlIfreq(socket, unix.SIOCGIFFLAGS, ifreq)
+	if err != nil {
+		return fmt.Errorf("failed to get loopback flags: %w", err)
+	}
+
+	ifreq.SetUint16(ifreq.Uint16() | unix.IFF_UP)
+
+	err = unix.IoctlIfreq(socket, unix.SIOCSIFFLAGS, ifreq)
+	if err != nil {
+		return fmt.Errorf("failed to bring up loopback: %w", err)
+	}
+
+	return nil
+}
+
+// pivot makes the new root the root of the mount namespace, and detaches the old one.
+func (s *sandbox) pivot() error {
+	err := unix.Chdir(s.Root)
+	if err != nil {
+		return fmt.Errorf("failed to change to root: %w", err)
+	}
+
+	err = unix.PivotRoot(".", ".")
+	if err != nil {
+		return fmt.Errorf("failed to pivot root: %w", err)
+	}
+
+	err = unix.Unmount(".", unix.MNT_DETACH)
+	if err != nil {
+		return fmt.Errorf("failed to detach old root: %w", err)
+	}
+
+	err = unix.Chdir(s.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to change to %s: %w", s.Dir, err)
+	}
+
+	return nil
+}
+
+// exec replaces the process with the command, in the environment of the task.
+func (s *sandbox) exec() error {
+	env := slices.DeleteFunc(os.Environ(), func(value string) bool {
+		return strings.HasPrefix(value, sandboxEnv+"=")
+	})
+
+	if os.Getenv("PATH") == "" {
+		_ = os.Setenv("PATH", s.Path)
+	}
+
+	path, err := exec.LookPath(s.Command[0])
+	if err != nil {
+		return fmt.Errorf("failed to find command: %w", err)
+	}
+
+	err = setResourceLimits(s.Resources)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Exec(path, s.Command, env)
+
+	return fmt.Errorf("failed to exec %s: %w", path, err)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package native
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// command fails, as namespaces are only available on linux.
+func (s *sandbox) command(_ context.Context, _ string, _ []string, _ []string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("sandboxes are %w", orchestra.ErrUnsupportedCapability)
+}
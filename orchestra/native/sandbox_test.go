@@ -0,0 +1,214 @@
+package native_test
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	_ "github.com/jtarchie/ci/orchestra/native"
+	"github.com/jtarchie/ci/runtime"
+	. "github.com/onsi/gomega"
+)
+
+// rootfs extracts a root filesystem with only a shell and the libraries it links to,
+// and a marker to tell it apart from the host.
+func rootfs(assert *WithT, dir string) {
+	shell, err := exec.LookPath("sh")
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	shell, err = filepath.EvalSymlinks(shell)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	files := map[string]string{"/bin/sh": shell}
+
+	output, err := exec.Command("ldd", shell).Output()
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	for _, library := range regexp.MustCompile(`(/\S+) \(`).FindAllStringSubmatch(string(output), -1) {
+		files[library[1]] = library[1]
+	}
+
+	for target, source := range files {
+		contents, err := os.ReadFile(source)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(dir, target)
+		assert.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		assert.Expect(os.WriteFile(path, contents, 0o755)).To(Succeed()) //nolint:gosec
+	}
+
+	assert.Expect(os.MkdirAll(filepath.Join(dir, "etc"), 0o755)).To(Succeed())
+	assert.Expect(os.WriteFile(filepath.Join(dir, "etc", "marker"), []byte("rootfs\n"), 0o600)).To(Succeed())
+}
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+
+	init, found := orchestra.Get("sandbox")
+	if !found {
+		t.Skip("sandboxes are not supported")
+	}
+
+	sandbox := func(assert *WithT, params url.Values) orchestra.Driver {
+		client, err := init(orchestra.Options{Endpoint: t.TempDir(), Namespace: "test", Params: params})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		return client
+	}
+
+	t.Run("has no network by default", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client := sandbox(assert, nil)
+		defer client.Close()
+
+		assert.Expect(client.Capabilities().Networking).To(BeFalse())
+		assert.Expect(client.Capabilities().Services).To(BeFalse())
+
+		// the interfaces of its own network namespace, after the two header lines
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", "tail -n +3 /proc/net/dev | cut -d: -f1"},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(strings.Fields(stdout)).To(Equal([]string{"lo"}))
+
+		client = sandbox(assert, url.Values{"network": {"true"}})
+		defer client.Close()
+
+		assert.Expect(client.Capabilities().Networking).To(BeTrue())
+		assert.Expect(client.Capabilities().Services).To(BeTrue())
+	})
+
+	t.Run("does not see the home directory", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		home, err := os.UserHomeDir()
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		// the directory of the task is bound in, which would create its parents
+		if strings.HasPrefix(t.TempDir(), home+string(filepath.Separator)) {
+			t.Skip("the temp dir is in the home directory")
+		}
+
+		client := sandbox(assert, nil)
+		defer client.Close()
+
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", `test -e "$1" && echo visible || echo hidden`, "sh", home},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(strings.TrimSpace(stdout)).To(Equal("hidden"))
+	})
+
+	t.Run("cannot reach the directories of other tasks", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		endpoint := t.TempDir()
+
+		client, err := init(orchestra.Options{Endpoint: endpoint, Namespace: "test"})
+		assert.Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		mounts := orchestra.Mounts{{Name: "shared", Path: "/shared"}}
+
+		status, _ := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", "echo private > secret && echo public > shared/file"},
+			Mounts:  mounts,
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+
+		// the state of the task is next to its directory
+		states, err := filepath.Glob(filepath.Join(endpoint, "*", "test-*.json"))
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(states).To(HaveLen(1))
+
+		sibling := strings.TrimSuffix(states[0], ".json")
+		assert.Expect(sibling).To(BeADirectory())
+
+		// the volume is shared, but the directory of the other task is not there
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", `cat "$1/secret"; touch "$1/tampered"; cat shared/file`, "sh", sibling},
+			Mounts:  mounts,
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(stdout).NotTo(ContainSubstring("private"))
+		assert.Expect(stdout).To(HaveSuffix("public\n"))
+		assert.Expect(filepath.Join(sibling, "tampered")).NotTo(BeAnExistingFile())
+	})
+
+	t.Run("system directories are read-only", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		client := sandbox(assert, nil)
+		defer client.Close()
+
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", "touch /usr/sandbox 2>&1; touch /etc/sandbox 2>&1; touch task"},
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(strings.Count(stdout, "Read-only file system")).To(Equal(2))
+		assert.Expect("/usr/sandbox").NotTo(BeAnExistingFile())
+	})
+
+	t.Run("uses a rootfs image as the root", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		dir := t.TempDir()
+		rootfs(assert, dir)
+
+		client := sandbox(assert, nil)
+		defer client.Close()
+
+		assert.Expect(client.Capabilities().Validate(orchestra.Task{Image: "rootfs:" + dir})).To(Succeed())
+		assert.Expect(client.Capabilities().Validate(orchestra.Task{Image: "alpine"})).
+			To(MatchError(orchestra.ErrUnsupportedCapability))
+
+		status, stdout := runTask(assert, client, orchestra.Task{
+			Command: []string{"sh", "-c", `read marker < /etc/marker; test -d /usr && echo host || echo "$marker"`},
+			Image:   "rootfs:" + dir,
+		})
+		assert.Expect(status.ExitCode()).To(Equal(0))
+		assert.Expect(strings.TrimSpace(stdout)).To(Equal("rootfs"))
+	})
+
+	t.Run("resolves a rootfs relative to the pipeline", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		dir := t.TempDir()
+		rootfs(assert, filepath.Join(dir, "images", "shell"))
+
+		client := sandbox(assert, nil)
+		defer client.Close()
+
+		runner := runtime.NewPipelineRunner(client, dir, false)
+
+		result := runner.Run(runtime.RunInput{
+			Command: []string{"sh", "-c", `read marker < /etc/marker; echo "$marker"`},
+			Image:   "rootfs:images/shell",
+			Name:    "rootfs",
+		})
+		assert.Expect(result.Error).To(BeEmpty())
+		assert.Expect(result.Code).To(Equal(0))
+		assert.Expect(strings.TrimSpace(result.Stdout)).To(Equal("rootfs"))
+
+		result = runner.Run(runtime.RunInput{Command: []string{"true"}, Image: "alpine", Name: "registry"})
+		assert.Expect(result.Error).To(ContainSubstring(orchestra.ErrUnsupportedCapability.Error()))
+	})
+}
@@ -0,0 +1,115 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+type NativeService struct {
+	*NativeContainer
+	ports map[int]int
+}
+
+// Host implements orchestra.ServiceContainer.
+func (n *NativeService) Host() string {
+	return "127.0.0.1"
+}
+
+// Port implements orchestra.ServiceContainer.
+func (n *NativeService) Port(port int) int {
+	return n.ports[port]
+}
+
+// RunService implements orchestra.ServiceDriver.
+// Services share the network of the host, so every port is allocated a free one on localhost.
+// The allocated ports are passed as `PORT_<port>`, and `PORT` when there is only one.
+// A service that is already running keeps the ports it was allocated.
+func (n *Native) RunService(ctx context.Context, service orchestra.Service) (orchestra.ServiceContainer, error) {
+	n.mutex.Lock()
+	existing, ok := n.containers[service.ID]
+	n.mutex.Unlock()
+
+	if ok {
+		ports := map[int]int{}
+
+		for _, port := range service.Ports {
+			ports[port], _ = strconv.Atoi(existing.task.Env["PORT_"+strconv.Itoa(port)])
+		}
+
+		return &NativeService{
+			NativeContainer: existing,
+			ports:           ports,
+		}, nil
+	}
+
+	ports := map[int]int{}
+	env := maps.Clone(service.Env)
+
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	for _, port := range service.Ports {
+		allocated, err := freePort()
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate port: %w", err)
+		}
+
+		ports[port] = allocated
+		env["PORT_"+strconv.Itoa(port)] = strconv.Itoa(allocated)
+
+		if len(service.Ports) == 1 {
+			env["PORT"] = strconv.Itoa(allocated)
+		}
+	}
+
+	task := service.Task
+	task.Env = env
+
+	if task.Readiness != nil {
+		probe := *task.Readiness
+		if allocated, ok := ports[probe.TCPPort]; ok {
+			probe.TCPPort = allocated
+		}
+
+		if probe.HTTPGet != nil {
+			httpGet := *probe.HTTPGet
+			if allocated, ok := ports[httpGet.Port]; ok {
+				httpGet.Port = allocated
+			}
+
+			probe.HTTPGet = &httpGet
+		}
+
+		task.Readiness = &probe
+	}
+
+	container, err := n.RunContainer(ctx, task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run service: %w", err)
+	}
+
+	nativeContainer, _ := container.(*NativeContainer)
+
+	return &NativeService{
+		NativeContainer: nativeContainer,
+		ports:           ports,
+	}, nil
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to listen: %w", err)
+	}
+	defer listener.Close()
+
+	address, _ := listener.Addr().(*net.TCPAddr)
+
+	return address.Port, nil
+}
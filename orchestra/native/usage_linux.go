@@ -0,0 +1,28 @@
+package native
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// blockSize is the unit rusage counts reads and writes in.
+const blockSize = 512
+
+// processUsage is the rusage of the process once it has exited,
+// it covers the command and the children it waited for.
+func processUsage(state *os.ProcessState) orchestra.Usage {
+	usage := orchestra.Usage{
+		CPUTime: state.UserTime() + state.SystemTime(),
+	}
+
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// maxrss is in kilobytes on linux
+		usage.PeakMemory = uint64(rusage.Maxrss) * 1024       //nolint:gosec
+		usage.ReadBytes = uint64(rusage.Inblock) * blockSize  //nolint:gosec
+		usage.WriteBytes = uint64(rusage.Oublock) * blockSize //nolint:gosec
+	}
+
+	return usage
+}
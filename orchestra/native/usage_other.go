@@ -0,0 +1,16 @@
+//go:build !linux
+
+package native
+
+import (
+	"os"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// processUsage only has the cpu time, as the units of rusage differ between platforms.
+func processUsage(state *os.ProcessState) orchestra.Usage {
+	return orchestra.Usage{
+		CPUTime: state.UserTime() + state.SystemTime(),
+	}
+}
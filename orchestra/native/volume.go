@@ -4,25 +4,98 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
 )
 
+// NativeVolume is a directory that tasks link to.
+// Its size is checked while tasks run, as a best effort: a task can write past it
+// in between checks, and is only failed once it is found.
 type NativeVolume struct {
-	path string
+	driver *Native
+	name   string
+	path   string
+	size   int
 }
 
 // Cleanup implements orchestra.Volume.
+// It removes the directory of the volume, the links tasks have to it are left dangling.
 func (n *NativeVolume) Cleanup(ctx context.Context) error {
+	err := removeAll(n.path)
+	if err != nil {
+		return fmt.Errorf("failed to remove volume: %w", err)
+	}
+
+	if n.driver != nil {
+		n.driver.volumesMutex.Lock()
+		defer n.driver.volumesMutex.Unlock()
+
+		if n.driver.volumes[n.name] == n {
+			delete(n.driver.volumes, n.name)
+		}
+	}
+
+	return nil
+}
+
+// Export implements orchestra.Volume.
+func (n *NativeVolume) Export(ctx context.Context, path string) (io.ReadCloser, error) {
+	source, err := archive.Join(n.path, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export: %w", err)
+	}
+
+	_, err = os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export: %w", err)
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(archive.Tar(source, writer, nil))
+	}()
+
+	return reader, nil
+}
+
+// Import implements orchestra.Volume.
+func (n *NativeVolume) Import(ctx context.Context, path string, tar io.Reader) error {
+	destination, err := archive.Join(n.path, path)
+	if err != nil {
+		return fmt.Errorf("failed to import: %w", err)
+	}
+
+	err = archive.Untar(tar, destination)
+	if err != nil {
+		return fmt.Errorf("failed to import: %w", err)
+	}
+
 	return nil
 }
 
 var ErrInvalidPath = errors.New("path is not in the container directory")
 
+// CreateVolume implements orchestra.Driver.
+// A volume that exists is returned, unless it was created with a different size.
 func (n *Native) CreateVolume(ctx context.Context, name string, size int) (orchestra.Volume, error) {
+	n.volumesMutex.Lock()
+	defer n.volumesMutex.Unlock()
+
+	if volume, ok := n.volumes[name]; ok {
+		if size > 0 && volume.size != size {
+			return nil, fmt.Errorf("%w: %s", orchestra.ErrVolumeSizeMismatch, name)
+		}
+
+		return volume, nil
+	}
+
 	path, err := filepath.Abs(filepath.Join(n.path, name))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
@@ -37,7 +110,61 @@ func (n *Native) CreateVolume(ctx context.Context, name string, size int) (orche
 		return nil, fmt.Errorf("failed to create path: %w", err)
 	}
 
-	return &NativeVolume{
-		path: path,
-	}, nil
+	volume := &NativeVolume{
+		driver: n,
+		name:   name,
+		path:   path,
+		size:   size,
+	}
+
+	if n.volumes == nil {
+		n.volumes = map[string]*NativeVolume{}
+	}
+
+	n.volumes[name] = volume
+
+	return volume, nil
+}
+
+// usage is the total size of the regular files in the volume.
+func (n *NativeVolume) usage() (int64, error) {
+	var total int64
+
+	err := filepath.WalkDir(n.path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat: %w", err)
+		}
+
+		total += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk volume: %w", err)
+	}
+
+	return total, nil
+}
+
+// isExceeded is true when the volume has a size and its files use more than it.
+func (n *NativeVolume) isExceeded() bool {
+	if n.size <= 0 {
+		return false
+	}
+
+	usage, err := n.usage()
+	if err != nil {
+		return false
+	}
+
+	return usage > int64(n.size)
 }
@@ -0,0 +1,28 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitExited blocks until the command has exited, without reaping it,
+// so its pid and process group cannot be reused yet.
+func waitExited(command *exec.Cmd) error {
+	var info unix.Siginfo
+
+	for {
+		err := unix.Waitid(unix.P_PID, command.Process.Pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to wait for command: %w", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package orchestra
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Options configure a driver when it is initialized.
+type Options struct {
+	// Attach uses the resources of an existing namespace, such as to hijack a container,
+	// without creating or removing any.
+	Attach bool
+	// Endpoint is the driver specific location, such as the docker host or native base directory.
+	Endpoint string
+	// Keep leaves containers and volumes behind when the driver is closed.
+	Keep bool
+	// Labels are attached to every resource the driver creates.
+	Labels map[string]string
+	// Namespace is used to group all resources a driver creates.
+	Namespace string
+	// Params are the remaining driver specific query parameters.
+	Params url.Values
+}
+
+var ErrInvalidOrchestrator = errors.New("invalid orchestrator")
+
+// ParseURL parses an orchestrator string into its driver name and options.
+// The string is either a driver name (`native`) or URL-style, where everything
+// between `://` and the query string is the endpoint, for example
+// `docker://unix:///var/run/docker.sock?network=ci` or `native:///var/ci?keep=true`.
+func ParseURL(orchestrator string, namespace string) (string, Options, error) {
+	options := Options{
+		Labels:    map[string]string{},
+		Namespace: namespace,
+		Params:    url.Values{},
+	}
+
+	location, query, _ := strings.Cut(orchestrator, "?")
+
+	driverName, endpoint, _ := strings.Cut(location, "://")
+	if driverName == "" {
+		return "", options, fmt.Errorf("%w: missing driver name in %q", ErrInvalidOrchestrator, orchestrator)
+	}
+
+	options.Endpoint = endpoint
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", options, fmt.Errorf("%w: could not parse query: %w", ErrInvalidOrchestrator, err)
+	}
+
+	if params.Has("namespace") {
+		options.Namespace = params.Get("namespace")
+		params.Del("namespace")
+	}
+
+	if params.Has("keep") {
+		options.Keep, err = strconv.ParseBool(params.Get("keep"))
+		if err != nil {
+			return "", options, fmt.Errorf("%w: could not parse keep: %w", ErrInvalidOrchestrator, err)
+		}
+
+		params.Del("keep")
+	}
+
+	if options.Namespace == "" {
+		return "", options, fmt.Errorf("%w: namespace cannot be empty", ErrInvalidOrchestrator)
+	}
+
+	options.Params = params
+
+	return driverName, options, nil
+}
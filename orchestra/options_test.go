@@ -0,0 +1,67 @@
+package orchestra_test
+
+import (
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("driver name only", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		name, options, err := orchestra.ParseURL("native", "ci")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(name).To(Equal("native"))
+		assert.Expect(options.Endpoint).To(BeEmpty())
+		assert.Expect(options.Namespace).To(Equal("ci"))
+		assert.Expect(options.Keep).To(BeFalse())
+	})
+
+	t.Run("docker host with params", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		name, options, err := orchestra.ParseURL("docker://unix:///var/run/docker.sock?network=ci&namespace=other", "ci")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(name).To(Equal("docker"))
+		assert.Expect(options.Endpoint).To(Equal("unix:///var/run/docker.sock"))
+		assert.Expect(options.Namespace).To(Equal("other"))
+		assert.Expect(options.Params.Get("network")).To(Equal("ci"))
+		assert.Expect(options.Params.Has("namespace")).To(BeFalse())
+	})
+
+	t.Run("native base dir with keep", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		name, options, err := orchestra.ParseURL("native:///var/ci?keep=true", "ci")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(name).To(Equal("native"))
+		assert.Expect(options.Endpoint).To(Equal("/var/ci"))
+		assert.Expect(options.Keep).To(BeTrue())
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		for _, orchestrator := range []string{
+			"://localhost",
+			"native?keep=maybe",
+			"native?namespace=",
+			"native?%zz",
+		} {
+			_, _, err := orchestra.ParseURL(orchestrator, "ci")
+			assert.Expect(err).To(MatchError(orchestra.ErrInvalidOrchestrator), orchestrator)
+		}
+	})
+}
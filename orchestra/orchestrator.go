@@ -2,27 +2,65 @@ package orchestra
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
 )
 
 type ContainerStatus interface {
+	// IsDone is true once the state is final.
 	IsDone() bool
+	// IsReady is true when the container is running and its readiness probe, if any, has passed.
+	IsReady() bool
+	// IsOOMKilled is true when the container was killed for going over its memory limit.
+	IsOOMKilled() bool
 	ExitCode() int
+	State() State
+	// StartedAt and FinishedAt are zero until the container has started and finished.
+	StartedAt() time.Time
+	FinishedAt() time.Time
+	// Reason explains a state other than running or succeeded, such as the error that stopped it from starting.
+	Reason() string
 }
 
 type Container interface {
 	Cleanup(ctx context.Context) error
+	// Exec runs another command in the running container, returning its exit code.
+	Exec(ctx context.Context, command []string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+	// ID identifies the container to the driver, such as to hijack it later.
+	ID() string
 	Logs(ctx context.Context, stdout, stderr io.Writer) error
 	Status(ctx context.Context) (ContainerStatus, error)
 }
 
+// ErrVolumeSizeMismatch is returned when a volume is created again with a different size,
+// as the size of a volume cannot be changed.
+var ErrVolumeSizeMismatch = errors.New("volume exists with a different size")
+
 type Volume interface {
 	Cleanup(ctx context.Context) error
+	// Export returns the contents of path, relative to the volume root, as a tar stream.
+	Export(ctx context.Context, path string) (io.ReadCloser, error)
+	// Import extracts a tar stream into path, relative to the volume root.
+	Import(ctx context.Context, path string, tar io.Reader) error
 }
 
 type Driver interface {
+	Capabilities() Capabilities
 	Close() error
 	CreateVolume(ctx context.Context, name string, size int) (Volume, error)
 	Name() string
 	RunContainer(ctx context.Context, task Task) (Container, error)
 }
+
+// ImagePuller is implemented by drivers that can pull images ahead of the tasks that use them.
+// The image of the task is pulled as it would be for running it, with its pull policy and registry credentials.
+type ImagePuller interface {
+	PullImage(ctx context.Context, task Task) error
+}
+
+// Hijacker is implemented by drivers that can open an interactive shell
+// in a container that was kept after its task failed.
+type Hijacker interface {
+	Hijack(ctx context.Context, containerID string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+}
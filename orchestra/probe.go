@@ -0,0 +1,50 @@
+package orchestra
+
+import "time"
+
+type HTTPGet struct {
+	Path string
+	Port int
+}
+
+// Probe checks whether a running task is ready, using exactly one of
+// a command, a TCP connection, or an HTTP GET returning a 2xx or 3xx status.
+type Probe struct {
+	Exec    []string
+	HTTPGet *HTTPGet
+	TCPPort int
+
+	Interval time.Duration
+	Retries  int
+	Timeout  time.Duration
+}
+
+const (
+	DefaultProbeInterval = time.Second
+	DefaultProbeRetries  = 30
+	DefaultProbeTimeout  = time.Second
+)
+
+// WithDefaults returns the probe with zero values replaced by defaults.
+func (p Probe) WithDefaults() Probe {
+	if p.Interval <= 0 {
+		p.Interval = DefaultProbeInterval
+	}
+
+	if p.Retries <= 0 {
+		p.Retries = DefaultProbeRetries
+	}
+
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultProbeTimeout
+	}
+
+	return p
+}
+
+// Deadline is the longest a probe can take before all of its retries have failed.
+func (p Probe) Deadline() time.Duration {
+	p = p.WithDefaults()
+
+	return time.Duration(p.Retries+1) * (p.Interval + p.Timeout)
+}
@@ -0,0 +1,39 @@
+package orchestra
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var errInvalidStat = errors.New("invalid stat")
+
+// processStartTime is when the process started, in clock ticks since boot.
+// A reused PID has a different start time, so together they identify a process.
+func processStartTime(pid int) (uint64, error) {
+	contents, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, fmt.Errorf("could not read stat: %w", err)
+	}
+
+	// the command name can contain spaces, the fields after it cannot
+	index := bytes.LastIndexByte(contents, ')')
+	if index < 0 {
+		return 0, fmt.Errorf("%w: %d", errInvalidStat, pid)
+	}
+
+	// starttime is the 22nd field, the 20th after the command name
+	fields := bytes.Fields(contents[index+1:])
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("%w: %d", errInvalidStat, pid)
+	}
+
+	startTime, err := strconv.ParseUint(string(fields[19]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %d: %w", errInvalidStat, pid, err)
+	}
+
+	return startTime, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package orchestra
+
+// processStartTime is unknown, so only the PID identifies a process.
+func processStartTime(_ int) (uint64, error) {
+	return 0, nil
+}
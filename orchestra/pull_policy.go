@@ -0,0 +1,29 @@
+package orchestra
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PullPolicy decides when a driver pulls the image of a task from its registry.
+type PullPolicy string
+
+const (
+	PullAlways       PullPolicy = "always"
+	PullIfNotPresent PullPolicy = "if-not-present"
+	// PullNever only uses images that are already present, it never contacts a registry.
+	PullNever PullPolicy = "never"
+)
+
+var ErrInvalidPullPolicy = errors.New("invalid pull policy")
+
+// ParsePullPolicy returns the policy for value, an empty value is an empty policy,
+// which lets the driver decide.
+func ParsePullPolicy(value string) (PullPolicy, error) {
+	switch policy := PullPolicy(value); policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidPullPolicy, value)
+	}
+}
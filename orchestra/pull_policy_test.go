@@ -0,0 +1,30 @@
+package orchestra_test
+
+import (
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	for value, expected := range map[string]orchestra.PullPolicy{
+		"":               "",
+		"always":         orchestra.PullAlways,
+		"if-not-present": orchestra.PullIfNotPresent,
+		"never":          orchestra.PullNever,
+	} {
+		policy, err := orchestra.ParsePullPolicy(value)
+		assert.Expect(err).NotTo(HaveOccurred(), value)
+		assert.Expect(policy).To(Equal(expected), value)
+	}
+
+	for _, value := range []string{"Always", "sometimes", "ifnotpresent"} {
+		_, err := orchestra.ParsePullPolicy(value)
+		assert.Expect(err).To(MatchError(orchestra.ErrInvalidPullPolicy), value)
+	}
+}
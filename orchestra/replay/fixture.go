@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+type StatusInteraction struct {
+	ExitCode    int             `json:"exit_code"`
+	FinishedAt  time.Time       `json:"finished_at"`
+	IsDone      bool            `json:"is_done"`
+	IsOOMKilled bool            `json:"is_oom_killed"`
+	IsReady     bool            `json:"is_ready"`
+	Reason      string          `json:"reason,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	State       orchestra.State `json:"state,omitempty"`
+}
+
+// ContainerInteraction is a task that was run, with the inputs that affect its output.
+type ContainerInteraction struct {
+	Command   []string            `json:"command"`
+	Env       map[string]string   `json:"env,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Image     string              `json:"image"`
+	Mounts    orchestra.Mounts    `json:"mounts,omitempty"`
+	Resources orchestra.Resources `json:"resources"`
+	Statuses  []StatusInteraction `json:"statuses"`
+	Stderr    string              `json:"stderr"`
+	Stdin     string              `json:"stdin,omitempty"`
+	Stdout    string              `json:"stdout"`
+	Timeout   time.Duration       `json:"timeout,omitempty"`
+}
+
+// portableMounts drops the directories of host mounts, which are different on every machine,
+// so they are matched by their name, path, and type instead.
+func portableMounts(mounts orchestra.Mounts) orchestra.Mounts {
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	portable := make(orchestra.Mounts, 0, len(mounts))
+
+	for _, mount := range mounts {
+		mount.Type = mount.Kind()
+		mount.HostPath = ""
+		portable = append(portable, mount)
+	}
+
+	return portable
+}
+
+type VolumeInteraction struct {
+	Error string `json:"error,omitempty"`
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+}
+
+// Fixture is the on-disk representation of a recorded driver session.
+// Containers and volumes are stored in the order they were requested.
+type Fixture struct {
+	Capabilities orchestra.Capabilities  `json:"capabilities"`
+	Containers   []*ContainerInteraction `json:"containers"`
+	Driver       string                  `json:"driver"`
+	Volumes      []*VolumeInteraction    `json:"volumes"`
+}
+
+func readFixture(filename string) (*Fixture, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fixture: %w", err)
+	}
+
+	var fixture Fixture
+
+	err = json.Unmarshal(contents, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal fixture: %w", err)
+	}
+
+	return &fixture, nil
+}
+
+func writeFixture(filename string, fixture *Fixture) error {
+	contents, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal fixture: %w", err)
+	}
+
+	err = os.WriteFile(filename, contents, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not write fixture: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	ErrMismatch      = errors.New("interaction does not match fixture")
+	ErrExhausted     = errors.New("no more interactions in fixture")
+	ErrNotReplayable = errors.New("interaction cannot be replayed")
+	ErrUnconsumed    = errors.New("fixture has interactions that were not replayed")
+)
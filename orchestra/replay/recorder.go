@@ -0,0 +1,300 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// Recorder wraps a driver and records every interaction with it,
+// writing them to a fixture file when the driver is closed.
+type Recorder struct {
+	driver   orchestra.Driver
+	filename string
+	fixture  *Fixture
+	mutex    sync.Mutex
+}
+
+func NewRecorder(driver orchestra.Driver, filename string) *Recorder {
+	recorder := &Recorder{
+		driver:   driver,
+		filename: filename,
+		fixture: &Fixture{
+			Capabilities: orchestra.Capabilities{},
+			Containers:   []*ContainerInteraction{},
+			Driver:       driver.Name(),
+			Volumes:      []*VolumeInteraction{},
+		},
+	}
+	recorder.fixture.Capabilities = recorder.Capabilities()
+
+	return recorder
+}
+
+// Capabilities implements orchestra.Driver.
+func (r *Recorder) Capabilities() orchestra.Capabilities {
+	return r.driver.Capabilities()
+}
+
+// BuildImage implements orchestra.ImageBuilder.
+// Builds are passed through without being recorded, so they cannot be replayed.
+func (r *Recorder) BuildImage(ctx context.Context, build orchestra.Build) (string, error) {
+	builder, ok := r.driver.(orchestra.ImageBuilder)
+	if !ok {
+		return "", fmt.Errorf("image builds are %w", orchestra.ErrUnsupportedCapability)
+	}
+
+	image, err := builder.BuildImage(ctx, build)
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+
+	return image, nil
+}
+
+// Hijack implements orchestra.Hijacker.
+func (r *Recorder) Hijack(ctx context.Context, containerID string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	hijacker, ok := r.driver.(orchestra.Hijacker)
+	if !ok {
+		return 0, fmt.Errorf("hijacking is %w", orchestra.ErrUnsupportedCapability)
+	}
+
+	code, err := hijacker.Hijack(ctx, containerID, stdin, stdout, stderr)
+	if err != nil {
+		return code, fmt.Errorf("failed to hijack: %w", err)
+	}
+
+	return code, nil
+}
+
+// PullImage implements orchestra.ImagePuller.
+// A driver that does not pull ahead of time has nothing to pull.
+func (r *Recorder) PullImage(ctx context.Context, task orchestra.Task) error {
+	puller, ok := r.driver.(orchestra.ImagePuller)
+	if !ok {
+		return nil
+	}
+
+	err := puller.PullImage(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	return nil
+}
+
+// RunService implements orchestra.ServiceDriver.
+// Services are passed through without being recorded, so they cannot be replayed.
+func (r *Recorder) RunService(ctx context.Context, service orchestra.Service) (orchestra.ServiceContainer, error) {
+	driver, ok := r.driver.(orchestra.ServiceDriver)
+	if !ok {
+		return nil, fmt.Errorf("services are %w", orchestra.ErrUnsupportedCapability)
+	}
+
+	container, err := driver.RunService(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run service: %w", err)
+	}
+
+	return container, nil
+}
+
+// Close implements orchestra.Driver.
+func (r *Recorder) Close() error {
+	closeErr := r.driver.Close()
+
+	err := r.Flush()
+	if err != nil {
+		return errors.Join(closeErr, err)
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("failed to close driver: %w", closeErr)
+	}
+
+	return nil
+}
+
+// Flush writes the interactions so far to the fixture file, without closing the driver,
+// for a run that leaves its containers behind.
+func (r *Recorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return writeFixture(r.filename, r.fixture)
+}
+
+// CreateVolume implements orchestra.Driver.
+func (r *Recorder) CreateVolume(ctx context.Context, name string, size int) (orchestra.Volume, error) {
+	volume, err := r.driver.CreateVolume(ctx, name, size)
+
+	interaction := &VolumeInteraction{
+		Name: name,
+		Size: size,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+
+	r.mutex.Lock()
+	r.fixture.Volumes = append(r.fixture.Volumes, interaction)
+	r.mutex.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume: %w", err)
+	}
+
+	return volume, nil
+}
+
+// Name implements orchestra.Driver.
+func (r *Recorder) Name() string {
+	return r.driver.Name()
+}
+
+// RunContainer implements orchestra.Driver.
+func (r *Recorder) RunContainer(ctx context.Context, task orchestra.Task) (orchestra.Container, error) {
+	interaction := &ContainerInteraction{
+		Command:   task.Command,
+		Env:       task.Env,
+		Image:     task.Image,
+		Mounts:    portableMounts(task.Mounts),
+		Resources: task.Resources,
+		Statuses:  []StatusInteraction{},
+		Timeout:   task.Timeout,
+	}
+
+	// stdin is read once, so it is kept to be given to the driver as well
+	if task.Stdin != nil {
+		stdin, err := io.ReadAll(task.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		interaction.Stdin = string(stdin)
+		task.Stdin = bytes.NewReader(stdin)
+	}
+
+	container, err := r.driver.RunContainer(ctx, task)
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+
+	r.mutex.Lock()
+	r.fixture.Containers = append(r.fixture.Containers, interaction)
+	r.mutex.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to run container: %w", err)
+	}
+
+	return &RecordedContainer{
+		container:   container,
+		interaction: interaction,
+		mutex:       &r.mutex,
+	}, nil
+}
+
+type RecordedContainer struct {
+	container   orchestra.Container
+	interaction *ContainerInteraction
+	mutex       *sync.Mutex
+}
+
+// Exec implements orchestra.Container.
+// Commands are passed through without being recorded.
+func (r *RecordedContainer) Exec(
+	ctx context.Context,
+	command []string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+) (int, error) {
+	code, err := r.container.Exec(ctx, command, stdin, stdout, stderr)
+	if err != nil {
+		return code, fmt.Errorf("failed to exec: %w", err)
+	}
+
+	return code, nil
+}
+
+// ID implements orchestra.Container.
+func (r *RecordedContainer) ID() string {
+	return r.container.ID()
+}
+
+// Cleanup implements orchestra.Container.
+func (r *RecordedContainer) Cleanup(ctx context.Context) error {
+	err := r.container.Cleanup(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup container: %w", err)
+	}
+
+	return nil
+}
+
+// Logs implements orchestra.Container.
+// Only the output of the most recent call is kept in the fixture.
+func (r *RecordedContainer) Logs(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
+	recordedStdout, recordedStderr := &strings.Builder{}, &strings.Builder{}
+
+	err := r.container.Logs(
+		ctx,
+		io.MultiWriter(stdout, recordedStdout),
+		io.MultiWriter(stderr, recordedStderr),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get logs: %w", err)
+	}
+
+	r.mutex.Lock()
+	r.interaction.Stdout = recordedStdout.String()
+	r.interaction.Stderr = recordedStderr.String()
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// Status implements orchestra.Container.
+// Consecutive identical statuses are collapsed, so polling frequency does not leak into the fixture.
+func (r *RecordedContainer) Status(ctx context.Context) (orchestra.ContainerStatus, error) {
+	status, err := r.container.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	interaction := StatusInteraction{
+		ExitCode:    status.ExitCode(),
+		FinishedAt:  status.FinishedAt(),
+		IsDone:      status.IsDone(),
+		IsOOMKilled: status.IsOOMKilled(),
+		IsReady:     status.IsReady(),
+		Reason:      status.Reason(),
+		StartedAt:   status.StartedAt(),
+		State:       status.State(),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	statuses := r.interaction.Statuses
+	if len(statuses) == 0 || statuses[len(statuses)-1] != interaction {
+		r.interaction.Statuses = append(statuses, interaction)
+	}
+
+	return status, nil
+}
+
+var (
+	_ orchestra.Driver        = &Recorder{}
+	_ orchestra.Hijacker      = &Recorder{}
+	_ orchestra.ImageBuilder  = &Recorder{}
+	_ orchestra.ImagePuller   = &Recorder{}
+	_ orchestra.ServiceDriver = &Recorder{}
+	_ orchestra.Container     = &RecordedContainer{}
+)
@@ -0,0 +1,169 @@
+package replay_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/native"
+	"github.com/jtarchie/ci/orchestra/replay"
+	. "github.com/onsi/gomega"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+
+	driver, err := native.NewNative(orchestra.Options{Namespace: "test"})
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	recorder := replay.NewRecorder(driver, fixture)
+
+	// the task has every input that is matched, with a host mount from a directory of this machine
+	task := func(hostPath string, command ...string) orchestra.Task {
+		taskID, err := uuid.NewV7()
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		return orchestra.Task{
+			ID:        taskID.String(),
+			Image:     "alpine",
+			Command:   command,
+			Env:       map[string]string{"GREETING": "hello"},
+			Mounts:    orchestra.Mounts{{Name: "input", Path: "input", HostPath: hostPath, ReadOnly: true}},
+			Resources: orchestra.Resources{Memory: 64 * 1024 * 1024},
+			Stdin:     strings.NewReader("from stdin\n"),
+		}
+	}
+
+	run := func(client orchestra.Driver, command ...string) (orchestra.Container, error) {
+		return client.RunContainer(context.Background(), task(t.TempDir(), command...))
+	}
+
+	container, err := run(recorder, "sh", "-c", "cat && echo $GREETING && exit 3")
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	assert.Eventually(func() bool {
+		status, err := container.Status(context.Background())
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		return status.IsDone() && status.ExitCode() == 3
+	}, "10s").Should(BeTrue())
+
+	stdout, stderr := &strings.Builder{}, &strings.Builder{}
+	err = container.Logs(context.Background(), stdout, stderr)
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(stdout.String()).To(ContainSubstring("from stdin\nhello"))
+
+	// the optional interfaces of the driver are passed through
+	service, err := recorder.RunService(context.Background(), orchestra.Service{
+		Name: "service",
+		Task: orchestra.Task{ID: "service", Command: []string{"sleep", "60"}},
+	})
+	assert.Expect(err).NotTo(HaveOccurred())
+	assert.Expect(service.Cleanup(context.Background())).To(Succeed())
+	assert.Expect(recorder.Capabilities().Services).To(BeTrue())
+
+	_, err = recorder.BuildImage(context.Background(), orchestra.Build{})
+	assert.Expect(err).To(MatchError(orchestra.ErrUnsupportedCapability))
+
+	err = recorder.Close()
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	t.Run("replays recorded interactions", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		replayer, err := replay.NewReplayer(fixture)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(replayer.Name()).To(Equal("native"))
+
+		// on another machine, the host mount is in another directory
+		container, err := run(replayer, "sh", "-c", "cat && echo $GREETING && exit 3")
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		assert.Eventually(func() bool {
+			status, err := container.Status(context.Background())
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			return status.IsDone() && status.ExitCode() == 3
+		}).Should(BeTrue())
+
+		stdout, stderr := &strings.Builder{}, &strings.Builder{}
+		err = container.Logs(context.Background(), stdout, stderr)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(stdout.String()).To(ContainSubstring("hello"))
+
+		err = replayer.Close()
+		assert.Expect(err).NotTo(HaveOccurred())
+	})
+
+	t.Run("fails on a different command", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		replayer, err := replay.NewReplayer(fixture)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		_, err = run(replayer, "echo", "goodbye")
+		assert.Expect(err).To(MatchError(replay.ErrMismatch))
+	})
+
+	t.Run("fails on different inputs", func(t *testing.T) {
+		t.Parallel()
+
+		changes := map[string]func(task *orchestra.Task){
+			"env":       func(task *orchestra.Task) { task.Env["GREETING"] = "goodbye" },
+			"stdin":     func(task *orchestra.Task) { task.Stdin = strings.NewReader("other") },
+			"resources": func(task *orchestra.Task) { task.Resources.Memory = 0 },
+			"mounts":    func(task *orchestra.Task) { task.Mounts[0].Path = "other" },
+		}
+
+		for name, change := range changes {
+			assert := NewGomegaWithT(t)
+
+			replayer, err := replay.NewReplayer(fixture)
+			assert.Expect(err).NotTo(HaveOccurred())
+
+			changed := task(t.TempDir(), "sh", "-c", "cat && echo $GREETING && exit 3")
+			change(&changed)
+
+			_, err = replayer.RunContainer(context.Background(), changed)
+			assert.Expect(err).To(MatchError(replay.ErrMismatch), name)
+		}
+	})
+
+	t.Run("services and builds cannot be replayed", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		replayer, err := replay.NewReplayer(fixture)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		_, err = replayer.RunService(context.Background(), orchestra.Service{Name: "service"})
+		assert.Expect(err).To(MatchError(replay.ErrNotReplayable))
+
+		_, err = replayer.BuildImage(context.Background(), orchestra.Build{})
+		assert.Expect(err).To(MatchError(replay.ErrNotReplayable))
+	})
+
+	t.Run("fails when interactions are not replayed", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		replayer, err := replay.NewReplayer(fixture)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = replayer.Close()
+		assert.Expect(err).To(MatchError(replay.ErrUnconsumed))
+	})
+}
@@ -0,0 +1,313 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// Replayer serves the interactions of a fixture back in the order they were recorded.
+// It never contacts a real driver, so differences between a run and its fixture
+// surface as errors instead of side effects.
+type Replayer struct {
+	containers int
+	fixture    *Fixture
+	mutex      sync.Mutex
+	volumes    int
+}
+
+func NewReplayer(filename string) (*Replayer, error) {
+	fixture, err := readFixture(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not load replay: %w", err)
+	}
+
+	return &Replayer{
+		fixture: fixture,
+	}, nil
+}
+
+// Capabilities implements orchestra.Driver.
+// The capabilities of the recorded driver are reported, so validation behaves the same.
+func (r *Replayer) Capabilities() orchestra.Capabilities {
+	return r.fixture.Capabilities
+}
+
+// BuildImage implements orchestra.ImageBuilder.
+// Builds are not recorded, so they cannot be replayed.
+func (r *Replayer) BuildImage(ctx context.Context, build orchestra.Build) (string, error) {
+	return "", fmt.Errorf("%w: build of %q", ErrNotReplayable, build.Tag)
+}
+
+// PullImage implements orchestra.ImagePuller.
+// Containers are not really run, so their images are not needed.
+func (r *Replayer) PullImage(ctx context.Context, task orchestra.Task) error {
+	return nil
+}
+
+// RunService implements orchestra.ServiceDriver.
+// Services are not recorded, so they cannot be replayed.
+func (r *Replayer) RunService(ctx context.Context, service orchestra.Service) (orchestra.ServiceContainer, error) {
+	return nil, fmt.Errorf("%w: service %q", ErrNotReplayable, service.Name)
+}
+
+// Close implements orchestra.Driver.
+func (r *Replayer) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.containers < len(r.fixture.Containers) || r.volumes < len(r.fixture.Volumes) {
+		return fmt.Errorf(
+			"%w: replayed %d of %d containers and %d of %d volumes",
+			ErrUnconsumed,
+			r.containers, len(r.fixture.Containers),
+			r.volumes, len(r.fixture.Volumes),
+		)
+	}
+
+	return nil
+}
+
+// CreateVolume implements orchestra.Driver.
+func (r *Replayer) CreateVolume(ctx context.Context, name string, size int) (orchestra.Volume, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.volumes >= len(r.fixture.Volumes) {
+		return nil, fmt.Errorf("%w: volume %q", ErrExhausted, name)
+	}
+
+	interaction := r.fixture.Volumes[r.volumes]
+	r.volumes++
+
+	if interaction.Name != name || interaction.Size != size {
+		return nil, fmt.Errorf(
+			"%w: expected volume %q (%d), got %q (%d)",
+			ErrMismatch, interaction.Name, interaction.Size, name, size,
+		)
+	}
+
+	if interaction.Error != "" {
+		//nolint:err113
+		return nil, errors.New(interaction.Error)
+	}
+
+	return &ReplayedVolume{}, nil
+}
+
+// Name implements orchestra.Driver.
+func (r *Replayer) Name() string {
+	return r.fixture.Driver
+}
+
+// RunContainer implements orchestra.Driver.
+func (r *Replayer) RunContainer(ctx context.Context, task orchestra.Task) (orchestra.Container, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.containers >= len(r.fixture.Containers) {
+		return nil, fmt.Errorf("%w: container %q", ErrExhausted, task.ID)
+	}
+
+	interaction := r.fixture.Containers[r.containers]
+	r.containers++
+
+	if interaction.Image != task.Image || !slices.Equal(interaction.Command, task.Command) {
+		return nil, fmt.Errorf(
+			"%w: expected %q with %q, got %q with %q",
+			ErrMismatch, interaction.Image, interaction.Command, task.Image, task.Command,
+		)
+	}
+
+	mounts := portableMounts(task.Mounts)
+	if !slices.Equal(interaction.Mounts, mounts) {
+		return nil, fmt.Errorf(
+			"%w: expected mounts %v, got %v",
+			ErrMismatch, interaction.Mounts, mounts,
+		)
+	}
+
+	if !maps.Equal(interaction.Env, task.Env) {
+		return nil, fmt.Errorf("%w: expected env %v, got %v", ErrMismatch, interaction.Env, task.Env)
+	}
+
+	if interaction.Resources != task.Resources || interaction.Timeout != task.Timeout {
+		return nil, fmt.Errorf(
+			"%w: expected resources %+v and timeout %s, got %+v and %s",
+			ErrMismatch, interaction.Resources, interaction.Timeout, task.Resources, task.Timeout,
+		)
+	}
+
+	stdin := ""
+
+	if task.Stdin != nil {
+		contents, err := io.ReadAll(task.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		stdin = string(contents)
+	}
+
+	if interaction.Stdin != stdin {
+		return nil, fmt.Errorf("%w: expected stdin %q, got %q", ErrMismatch, interaction.Stdin, stdin)
+	}
+
+	if interaction.Error != "" {
+		//nolint:err113
+		return nil, errors.New(interaction.Error)
+	}
+
+	return &ReplayedContainer{
+		id:          task.ID,
+		interaction: interaction,
+	}, nil
+}
+
+type ReplayedContainer struct {
+	id          string
+	interaction *ContainerInteraction
+	mutex       sync.Mutex
+	statuses    int
+}
+
+// Cleanup implements orchestra.Container.
+func (r *ReplayedContainer) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// Exec implements orchestra.Container.
+// Commands are not recorded, so they cannot be replayed.
+func (r *ReplayedContainer) Exec(
+	ctx context.Context,
+	command []string,
+	stdin io.Reader,
+	stdout, stderr io.Writer,
+) (int, error) {
+	return 0, fmt.Errorf("%w: exec of %q", ErrNotReplayable, command)
+}
+
+// ID implements orchestra.Container.
+func (r *ReplayedContainer) ID() string {
+	return r.id
+}
+
+// Logs implements orchestra.Container.
+func (r *ReplayedContainer) Logs(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
+	_, err := io.WriteString(stdout, r.interaction.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to copy stdout: %w", err)
+	}
+
+	_, err = io.WriteString(stderr, r.interaction.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to copy stderr: %w", err)
+	}
+
+	return nil
+}
+
+// Status implements orchestra.Container.
+// Once the recorded statuses run out, the last one is repeated.
+func (r *ReplayedContainer) Status(ctx context.Context) (orchestra.ContainerStatus, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	statuses := r.interaction.Statuses
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("%w: no status recorded", ErrExhausted)
+	}
+
+	index := min(r.statuses, len(statuses)-1)
+	r.statuses++
+
+	return &ReplayedStatus{
+		interaction: statuses[index],
+	}, nil
+}
+
+type ReplayedStatus struct {
+	interaction StatusInteraction
+}
+
+func (r *ReplayedStatus) ExitCode() int {
+	return r.interaction.ExitCode
+}
+
+func (r *ReplayedStatus) IsDone() bool {
+	return r.interaction.IsDone
+}
+
+func (r *ReplayedStatus) IsOOMKilled() bool {
+	return r.interaction.IsOOMKilled
+}
+
+func (r *ReplayedStatus) IsReady() bool {
+	return r.interaction.IsReady
+}
+
+// State implements orchestra.ContainerStatus.
+// Fixtures recorded before states existed have it derived from the other fields.
+func (r *ReplayedStatus) State() orchestra.State {
+	switch {
+	case r.interaction.State != "":
+		return r.interaction.State
+	case r.interaction.IsDone:
+		return orchestra.ExitState(r.interaction.ExitCode, r.interaction.IsOOMKilled)
+	default:
+		return orchestra.StateRunning
+	}
+}
+
+func (r *ReplayedStatus) Reason() string {
+	return r.interaction.Reason
+}
+
+func (r *ReplayedStatus) StartedAt() time.Time {
+	return r.interaction.StartedAt
+}
+
+func (r *ReplayedStatus) FinishedAt() time.Time {
+	return r.interaction.FinishedAt
+}
+
+type ReplayedVolume struct{}
+
+// Cleanup implements orchestra.Volume.
+func (r *ReplayedVolume) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// Export implements orchestra.Volume.
+// The contents of volumes are not recorded, so there is nothing to export.
+func (r *ReplayedVolume) Export(ctx context.Context, path string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("%w: volume contents are not recorded", ErrNotReplayable)
+}
+
+// Import implements orchestra.Volume.
+// The contents are discarded, as containers are not really run.
+func (r *ReplayedVolume) Import(ctx context.Context, path string, tar io.Reader) error {
+	_, err := io.Copy(io.Discard, tar)
+	if err != nil {
+		return fmt.Errorf("could not read import: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	_ orchestra.Driver          = &Replayer{}
+	_ orchestra.ImageBuilder    = &Replayer{}
+	_ orchestra.ImagePuller     = &Replayer{}
+	_ orchestra.ServiceDriver   = &Replayer{}
+	_ orchestra.Container       = &ReplayedContainer{}
+	_ orchestra.ContainerStatus = &ReplayedStatus{}
+	_ orchestra.Volume          = &ReplayedVolume{}
+)
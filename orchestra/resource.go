@@ -0,0 +1,123 @@
+package orchestra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Owner identifies the process that created a resource.
+// StartTime tells it apart from a later process that reused its PID,
+// it is zero when the platform does not report it.
+type Owner struct {
+	Host      string
+	PID       int
+	StartTime uint64
+}
+
+func CurrentOwner() Owner {
+	hostname, _ := os.Hostname()
+	pid := os.Getpid()
+	startTime, _ := processStartTime(pid)
+
+	return Owner{
+		Host:      hostname,
+		PID:       pid,
+		StartTime: startTime,
+	}
+}
+
+func (o Owner) String() string {
+	if o.StartTime == 0 {
+		return fmt.Sprintf("%s:%d", o.Host, o.PID)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", o.Host, o.PID, o.StartTime)
+}
+
+var ErrInvalidOwner = errors.New("invalid owner")
+
+// ParseOwner parses the "host:pid:starttime" of Owner.String,
+// the start time is optional.
+func ParseOwner(value string) (Owner, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Owner{}, fmt.Errorf("%w: %q", ErrInvalidOwner, value)
+	}
+
+	processID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Owner{}, fmt.Errorf("%w: %q: %w", ErrInvalidOwner, value, err)
+	}
+
+	owner := Owner{
+		Host: parts[0],
+		PID:  processID,
+	}
+
+	if len(parts) == 3 {
+		owner.StartTime, err = strconv.ParseUint(parts[2], 10, 64)
+		if err != nil {
+			return Owner{}, fmt.Errorf("%w: %q: %w", ErrInvalidOwner, value, err)
+		}
+	}
+
+	return owner, nil
+}
+
+// IsAlive reports whether the owning process is still running,
+// and not a process that reused its PID.
+// Processes on other hosts cannot be checked, so they are assumed to be alive.
+func (o Owner) IsAlive() bool {
+	if o.Host == "" || o.PID == 0 {
+		return false
+	}
+
+	if o.Host != CurrentOwner().Host {
+		return true
+	}
+
+	process, err := os.FindProcess(o.PID)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	if err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+
+	if o.StartTime == 0 {
+		return true
+	}
+
+	// when the start time cannot be read, assume it is the same process
+	startTime, err := processStartTime(o.PID)
+	if err != nil || startTime == 0 {
+		return true
+	}
+
+	return startTime == o.StartTime
+}
+
+// Resource is anything a driver leaves behind, such as a container or volume.
+type Resource struct {
+	CreatedAt time.Time
+	ID        string
+	Kind      string
+	Labels    map[string]string
+	Namespace string
+	Owner     Owner
+}
+
+// Collector is implemented by drivers that can find and remove
+// the resources of every namespace, not just their own.
+type Collector interface {
+	Resources(ctx context.Context) ([]Resource, error)
+	RemoveResource(ctx context.Context, resource Resource) error
+}
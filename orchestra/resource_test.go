@@ -0,0 +1,110 @@
+package orchestra_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseOwner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trips the current owner", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		owner := orchestra.CurrentOwner()
+		assert.Expect(owner.PID).To(Equal(os.Getpid()))
+
+		parsed, err := orchestra.ParseOwner(owner.String())
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(parsed).To(Equal(owner))
+	})
+
+	t.Run("start time is optional", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		owner, err := orchestra.ParseOwner("host:123")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(owner).To(Equal(orchestra.Owner{Host: "host", PID: 123}))
+
+		owner, err = orchestra.ParseOwner("host:123:456")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(owner).To(Equal(orchestra.Owner{Host: "host", PID: 123, StartTime: 456}))
+	})
+
+	t.Run("rejects invalid owners", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		for _, value := range []string{"", "host", "host:pid", "host:1:start", "host:1:2:3"} {
+			_, err := orchestra.ParseOwner(value)
+			assert.Expect(err).To(MatchError(orchestra.ErrInvalidOwner), value)
+		}
+	})
+}
+
+func TestOwnerIsAlive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the current process", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		assert.Expect(orchestra.CurrentOwner().IsAlive()).To(BeTrue())
+	})
+
+	t.Run("another host is assumed alive", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		assert.Expect(orchestra.Owner{Host: "some-other-host", PID: 1}.IsAlive()).To(BeTrue())
+	})
+
+	t.Run("an empty owner", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		assert.Expect(orchestra.Owner{}.IsAlive()).To(BeFalse())
+	})
+
+	t.Run("a process that exited", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		command := exec.Command("true")
+		assert.Expect(command.Run()).To(Succeed())
+
+		owner := orchestra.CurrentOwner()
+		owner.PID = command.Process.Pid
+		owner.StartTime = 0
+
+		assert.Expect(owner.IsAlive()).To(BeFalse())
+	})
+
+	t.Run("a process that reused the PID", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		owner := orchestra.CurrentOwner()
+		if owner.StartTime == 0 {
+			t.Skip("process start times are not supported")
+		}
+
+		owner.StartTime++
+
+		assert.Expect(owner.IsAlive()).To(BeFalse())
+	})
+}
@@ -0,0 +1,15 @@
+package orchestra
+
+// Resources limit what a task can consume, zero values are unlimited.
+type Resources struct {
+	// CPU is the number of CPUs, fractions are allowed.
+	CPU float64
+	// Memory is in bytes.
+	Memory int64
+	// Pids is the number of processes, native counts every process of the user.
+	Pids int64
+}
+
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
@@ -0,0 +1,24 @@
+package orchestra
+
+import "context"
+
+// Service is a long-running task, such as a database, that other tasks connect to.
+type Service struct {
+	Task
+	// Name is the hostname other tasks reach the service on, where the driver supports it.
+	Name  string
+	Ports []int
+}
+
+// ServiceContainer is a running service and the address other tasks can reach it on.
+type ServiceContainer interface {
+	Container
+	Host() string
+	// Port returns where a port the service listens on is reachable from other tasks.
+	Port(port int) int
+}
+
+// ServiceDriver is implemented by drivers that can run services alongside tasks.
+type ServiceDriver interface {
+	RunService(ctx context.Context, service Service) (ServiceContainer, error)
+}
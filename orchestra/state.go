@@ -0,0 +1,41 @@
+package orchestra
+
+// State is where a container is in its lifecycle.
+type State string
+
+const (
+	// StatePending has been created, but has not started yet.
+	StatePending State = "pending"
+	StateRunning State = "running"
+	// StateSucceeded exited with a zero exit code.
+	StateSucceeded State = "succeeded"
+	// StateFailed exited with a non-zero exit code.
+	StateFailed State = "failed"
+	// StateErrored could not be run, or was lost by the driver.
+	StateErrored   State = "errored"
+	StateOOMKilled State = "oom-killed"
+	// StateTimedOut was killed for running longer than the timeout of its task.
+	StateTimedOut State = "timed-out"
+)
+
+// IsDone is true when the container will not change state anymore.
+func (s State) IsDone() bool {
+	switch s {
+	case StatePending, StateRunning:
+		return false
+	default:
+		return true
+	}
+}
+
+// ExitState is the state of a container that exited by itself.
+func ExitState(exitCode int, oomKilled bool) State {
+	switch {
+	case oomKilled:
+		return StateOOMKilled
+	case exitCode == 0:
+		return StateSucceeded
+	default:
+		return StateFailed
+	}
+}
@@ -1,15 +1,70 @@
 package orchestra
 
+import (
+	"io"
+	"time"
+)
+
+type MountType string
+
+const (
+	// MountTypeVolume is a named volume, shared by the tasks that mount it.
+	MountTypeVolume MountType = "volume"
+	// MountTypeTmpfs is scratch space that only lasts for the task.
+	MountTypeTmpfs MountType = "tmpfs"
+	// MountTypeHost is a directory on the host.
+	MountTypeHost MountType = "host"
+)
+
 type Mount struct {
-	Name string
-	Path string
+	// HostPath is the directory on the host of a host mount.
+	HostPath string
+	Name     string
+	Path     string
+	// ReadOnly protects the contents of the mount from the task.
+	ReadOnly bool
+	// Size of the volume in bytes, zero is unlimited.
+	Size int
+	// Type defaults to a host mount when there is a host path, otherwise a volume.
+	Type MountType
+}
+
+// Kind is the type of the mount, with the default applied.
+func (m Mount) Kind() MountType {
+	if m.Type != "" {
+		return m.Type
+	}
+
+	if m.HostPath != "" {
+		return MountTypeHost
+	}
+
+	return MountTypeVolume
 }
 
 type Mounts []Mount
 
+// RegistryAuth are the credentials to pull the image of a task with.
+type RegistryAuth struct {
+	Password string
+	Username string
+}
+
 type Task struct {
 	Command []string
+	Env     map[string]string
 	ID      string
 	Image   string
 	Mounts  Mounts
+	// Stdin is given to the command, which sees the end of input once it has been read.
+	Stdin io.Reader
+	// PullPolicy of the image, empty uses the default of the driver.
+	PullPolicy PullPolicy
+	// RegistryAuth is used instead of the credentials the driver would find for the registry.
+	RegistryAuth *RegistryAuth
+	// Readiness is checked while the task is running, and is reported through ContainerStatus.
+	Readiness *Probe
+	Resources Resources
+	// Timeout kills the task when it runs for longer, zero is no timeout.
+	Timeout time.Duration
 }
@@ -0,0 +1,21 @@
+package orchestra
+
+import (
+	"context"
+	"time"
+)
+
+// Usage is what a container consumed while it ran.
+type Usage struct {
+	CPUTime time.Duration
+	// PeakMemory is the most memory used at once, in bytes.
+	PeakMemory uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+}
+
+// UsageReporter is implemented by containers that measure their resource usage.
+// The usage is complete once the container is done.
+type UsageReporter interface {
+	Usage(ctx context.Context) (Usage, error)
+}
@@ -1,8 +1,47 @@
 declare global {
   interface RunTaskConfig {
     name: string;
+    // a registry reference, or a tarball as "oci-archive:<path>" or "docker-archive:<path>",
+    // the sandbox driver takes an extracted root filesystem as "rootfs:<path>"
     image: string;
     command: string[];
+    env?: { [key: string]: string };
+    resources?: ContainerLimits;
+    mounts?: Mount[];
+    pull_policy?: PullPolicy;
+    registry_auth?: RegistryAuth;
+    // given to the command, which sees the end of input after it
+    stdin?: string;
+    // a duration like "10m", the task is killed when it runs for longer
+    timeout?: string;
+  }
+
+  // overrides the credentials found in the docker config file
+  interface RegistryAuth {
+    username: string;
+    password: string;
+  }
+
+  type PullPolicy = "always" | "if-not-present" | "never";
+
+  // an exported `images` is pulled before the pipeline starts, with the
+  // pull_policy and registry_auth of the tasks that use them
+  interface ImageConfig {
+    image: string;
+    pull_policy?: PullPolicy;
+    registry_auth?: RegistryAuth;
+  }
+
+  interface Mount {
+    name: string;
+    path: string;
+    size?: number;
+    // defaults to "host" when there is a host_path, otherwise "volume"
+    type?: "volume" | "tmpfs" | "host";
+    // a host directory, relative to the pipeline file, like "." for the checkout
+    host_path?: string;
+    // defaults to true for host mounts
+    read_only?: boolean;
   }
 
   interface RunTaskResult {
@@ -10,10 +49,95 @@ declare global {
     stderr: string;
     error: string;
     code: number;
+    oom_killed: boolean;
+    state:
+      | "succeeded"
+      | "failed"
+      | "errored"
+      | "oom-killed"
+      | "timed-out";
+    // in seconds
+    duration: number;
+    // null when the driver does not measure it
+    usage: TaskUsage | null;
+  }
+
+  interface TaskUsage {
+    // in seconds
+    cpu_time: number;
+    // in bytes
+    peak_memory: number;
+    read_bytes: number;
+    write_bytes: number;
   }
 
   function run(task: RunTaskConfig): RunTaskResult;
 
+  interface ReadinessProbe {
+    exec?: string[];
+    tcp_port?: number;
+    http_get?: { path: string; port: number };
+    interval?: string;
+    timeout?: string;
+    retries?: number;
+  }
+
+  interface ServiceConfig {
+    name: string;
+    image: string;
+    command?: string[];
+    env?: { [key: string]: string };
+    ports?: number[];
+    pull_policy?: PullPolicy;
+    readiness?: ReadinessProbe;
+    registry_auth?: RegistryAuth;
+  }
+
+  interface Service {
+    name: string;
+    host: string;
+    ports: { [port: string]: number };
+    error: string;
+    // runs a command in the service, such as a migration
+    exec(command: string[]): RunTaskResult;
+    stop(): void;
+  }
+
+  function service(config: ServiceConfig): Service;
+
+  interface VolumeConfig {
+    name: string;
+    size?: number;
+  }
+
+  interface Volume {
+    name: string;
+    error: string;
+    // copies a host directory into path within the volume
+    import(hostPath: string, path: string): void;
+    // copies path within the volume into a host directory
+    export(path: string, hostPath: string): void;
+  }
+
+  function volume(config: VolumeConfig): Volume;
+
+  interface BuildConfig {
+    // the build context, from a path in a volume the pipeline created,
+    // or on the host relative to the pipeline file
+    context: { volume?: string; host_path?: string; path?: string };
+    dockerfile?: string;
+    args?: { [key: string]: string };
+    tag?: string;
+  }
+
+  interface BuildResult {
+    // the image to give to run()
+    image: string;
+    error: string;
+  }
+
+  function build(config: BuildConfig): BuildResult;
+
   namespace assert {
     function containsElement(
       element: any,
@@ -30,8 +154,16 @@ declare global {
     function truthy(value: any, message?: string): void;
   }
 
+  interface ContainerLimits {
+    cpu?: number;
+    memory?: number;
+    pids?: number;
+  }
+
   interface TaskConfig {
     platform?: string;
+    container_limits?: ContainerLimits;
+    inputs?: { name: string; path?: string; host_path?: string }[];
     image_resource: {
       type: string;
       source: { [key: string]: string };
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeptTask is the container of a failed task, kept so it can be hijacked.
+type KeptTask struct {
+	ContainerID string `json:"container_id"`
+	Name        string `json:"name"`
+}
+
+// Run is an invocation of a pipeline that is still active,
+// was not able to unregister itself, or kept the containers of failed tasks.
+type Run struct {
+	ID           string     `json:"id"`
+	Kept         []KeptTask `json:"kept,omitempty"`
+	Namespace    string     `json:"namespace"`
+	Orchestrator string     `json:"orchestrator"`
+	Owner        string     `json:"owner"`
+	Pipeline     string     `json:"pipeline"`
+	StartedAt    time.Time  `json:"started_at"`
+}
+
+// Registry records runs as JSON files in a directory,
+// so separate processes on the same host can see each other.
+type Registry struct {
+	path string
+}
+
+func New(path string) (*Registry, error) {
+	if path == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("could not find cache dir: %w", err)
+		}
+
+		path = filepath.Join(cacheDir, "ci", "runs")
+	}
+
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not create registry: %w", err)
+	}
+
+	return &Registry{
+		path: path,
+	}, nil
+}
+
+var ErrRunNotFound = errors.New("run not found")
+
+// Register records the run, replacing it when it was already registered.
+// It is written to a temp file that is renamed into place,
+// so a concurrent reader never sees a partial run.
+func (r *Registry) Register(run Run) error {
+	contents, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("could not marshal run: %w", err)
+	}
+
+	file, err := os.CreateTemp(r.path, "."+filepath.Base(run.ID)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create run: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(contents)
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("could not write run: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("could not write run: %w", err)
+	}
+
+	err = os.Rename(file.Name(), r.filename(run.ID))
+	if err != nil {
+		return fmt.Errorf("could not register run: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Registry) Unregister(id string) error {
+	err := os.Remove(r.filename(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not unregister run: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Registry) Get(id string) (Run, error) {
+	contents, err := os.ReadFile(r.filename(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Run{}, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+
+	if err != nil {
+		return Run{}, fmt.Errorf("could not read run: %w", err)
+	}
+
+	var run Run
+
+	err = json.Unmarshal(contents, &run)
+	if err != nil {
+		return Run{}, fmt.Errorf("could not unmarshal run %s: %w", id, err)
+	}
+
+	return run, nil
+}
+
+// Runs lists the registered runs, those that cannot be read are logged and skipped.
+func (r *Registry) Runs() ([]Run, error) {
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read registry: %w", err)
+	}
+
+	runs := []Run{}
+
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), ".json")
+		if !found {
+			continue
+		}
+
+		run, err := r.Get(id)
+		if err != nil {
+			slog.Warn("registry.skip", "id", id, "err", err)
+
+			continue
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+func (r *Registry) filename(id string) string {
+	return filepath.Join(r.path, filepath.Base(id)+".json")
+}
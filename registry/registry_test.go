@@ -0,0 +1,120 @@
+package registry_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jtarchie/ci/registry"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("registers and unregisters runs", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		runs, err := registry.New(t.TempDir())
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		run := registry.Run{
+			ID:        "run",
+			Namespace: "ci-run",
+			Owner:     "host:1",
+			StartedAt: time.Now().UTC().Truncate(time.Second),
+		}
+
+		err = runs.Register(run)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		found, err := runs.Get("run")
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(found).To(Equal(run))
+
+		run.Kept = []registry.KeptTask{{ContainerID: "container", Name: "task"}}
+		err = runs.Register(run)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		all, err := runs.Runs()
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(all).To(Equal([]registry.Run{run}))
+
+		err = runs.Unregister("run")
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		_, err = runs.Get("run")
+		assert.Expect(err).To(MatchError(registry.ErrRunNotFound))
+
+		err = runs.Unregister("run")
+		assert.Expect(err).NotTo(HaveOccurred())
+	})
+
+	t.Run("skips runs that cannot be read", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		path := t.TempDir()
+
+		runs, err := registry.New(path)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = runs.Register(registry.Run{ID: "good"})
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(path, "bad.json"), []byte("{"), 0o600)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		all, err := runs.Runs()
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(all).To(HaveLen(1))
+		assert.Expect(all[0].ID).To(Equal("good"))
+	})
+
+	t.Run("concurrent registers leave whole runs", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		path := t.TempDir()
+
+		runs, err := registry.New(path)
+		assert.Expect(err).NotTo(HaveOccurred())
+
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for range 10 {
+					_ = runs.Register(registry.Run{ID: "run", Namespace: "ci-run"})
+				}
+			}()
+		}
+
+		for range 100 {
+			run, err := runs.Get("run")
+			if errors.Is(err, registry.ErrRunNotFound) {
+				continue
+			}
+
+			assert.Expect(err).NotTo(HaveOccurred())
+			assert.Expect(run.Namespace).To(Equal("ci-run"))
+		}
+
+		wg.Wait()
+
+		entries, err := os.ReadDir(path)
+		assert.Expect(err).NotTo(HaveOccurred())
+		assert.Expect(entries).To(HaveLen(1))
+	})
+}
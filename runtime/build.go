@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
+)
+
+// BuildContextInput is where the build context comes from, either a path in a volume
+// the pipeline has created, or a host path relative to the pipeline.
+type BuildContextInput struct {
+	HostPath string `js:"host_path" json:"host_path"`
+	Path     string `js:"path"      json:"path"`
+	Volume   string `js:"volume"    json:"volume"`
+}
+
+type BuildInput struct {
+	Args       map[string]string `js:"args"       json:"args"`
+	Context    BuildContextInput `js:"context"    json:"context"`
+	Dockerfile string            `js:"dockerfile" json:"dockerfile"`
+	Tag        string            `js:"tag"        json:"tag"`
+}
+
+// BuildResult has the image to give to later `run()` calls.
+type BuildResult struct {
+	Error string `js:"error" json:"error"`
+	Image string `js:"image" json:"image"`
+}
+
+var (
+	ErrBuildContextRequired = errors.New("build context needs a volume or a host path")
+	ErrVolumeNotFound       = errors.New("volume has not been created")
+)
+
+func (c *PipelineRunner) Build(input BuildInput) *BuildResult {
+	ctx := context.Background()
+
+	logger := c.log.With("orchestrator", c.client.Name())
+
+	logger.Info("image.build", "input", input)
+
+	builder, ok := c.client.(orchestra.ImageBuilder)
+	if !ok {
+		return &BuildResult{
+			Error: fmt.Sprintf("could not build image on %s: image builds are %s", c.client.Name(), orchestra.ErrUnsupportedCapability),
+		}
+	}
+
+	buildContext, err := c.buildContext(ctx, input.Context)
+	if err != nil {
+		return &BuildResult{Error: fmt.Sprintf("could not create build context: %s", err)}
+	}
+	defer buildContext.Close()
+
+	image, err := builder.BuildImage(ctx, orchestra.Build{
+		Args:       input.Args,
+		Context:    buildContext,
+		Dockerfile: input.Dockerfile,
+		Tag:        input.Tag,
+	})
+	if err != nil {
+		return &BuildResult{Error: fmt.Sprintf("could not build image: %s", err)}
+	}
+
+	logger.Info("image.built", "image", image)
+
+	return &BuildResult{Image: image}
+}
+
+// buildContext is a tar stream of the context, host paths skip what git ignores.
+func (c *PipelineRunner) buildContext(ctx context.Context, input BuildContextInput) (io.ReadCloser, error) {
+	switch {
+	case input.Volume != "" && input.HostPath != "":
+		return nil, fmt.Errorf("%w, not both", ErrBuildContextRequired)
+	case input.Volume != "":
+		if !c.volumes[input.Volume] {
+			return nil, fmt.Errorf("%w: %s", ErrVolumeNotFound, input.Volume)
+		}
+
+		// the volume exists, so this returns it rather than creating one
+		volume, err := c.client.CreateVolume(ctx, input.Volume, 0)
+		if err != nil {
+			return nil, fmt.Errorf("could not get volume: %w", err)
+		}
+
+		reader, err := volume.Export(ctx, input.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not export volume: %w", err)
+		}
+
+		return reader, nil
+	case input.HostPath != "":
+		hostPath, err := c.hostPath(input.HostPath)
+		if err != nil {
+			return nil, err
+		}
+
+		hostPath, err = archive.Join(hostPath, input.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve host path: %w", err)
+		}
+
+		reader, writer := io.Pipe()
+
+		go func() {
+			writer.CloseWithError(archive.Tar(hostPath, writer, archive.GitIgnore(hostPath)))
+		}()
+
+		return reader, nil
+	default:
+		return nil, ErrBuildContextRequired
+	}
+}
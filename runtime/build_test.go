@@ -0,0 +1,173 @@
+package runtime_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
+	"github.com/jtarchie/ci/runtime"
+	. "github.com/onsi/gomega"
+)
+
+// builder is a driver that records the files of the contexts it builds.
+type builder struct {
+	created []string
+	files   []string
+	volume  string
+}
+
+func (b *builder) Capabilities() orchestra.Capabilities {
+	return orchestra.Capabilities{Images: true, Volumes: true}
+}
+
+func (b *builder) Close() error { return nil }
+
+func (b *builder) CreateVolume(_ context.Context, name string, _ int) (orchestra.Volume, error) {
+	b.created = append(b.created, name)
+
+	return &contextVolume{dir: b.volume}, nil
+}
+
+func (b *builder) Name() string { return "builder" }
+
+func (b *builder) RunContainer(context.Context, orchestra.Task) (orchestra.Container, error) {
+	return nil, orchestra.ErrUnsupportedCapability
+}
+
+func (b *builder) BuildImage(_ context.Context, build orchestra.Build) (string, error) {
+	reader := tar.NewReader(build.Context)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return "built:" + build.Tag, nil
+		}
+
+		if err != nil {
+			return "", err //nolint:wrapcheck
+		}
+
+		b.files = append(b.files, header.Name)
+	}
+}
+
+// contextVolume exports a directory on the host.
+type contextVolume struct {
+	dir string
+}
+
+func (c *contextVolume) Cleanup(context.Context) error { return nil }
+
+func (c *contextVolume) Export(_ context.Context, path string) (io.ReadCloser, error) {
+	buffer := &bytes.Buffer{}
+
+	err := archive.Tar(filepath.Join(c.dir, path), buffer, nil)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return io.NopCloser(buffer), nil
+}
+
+func (c *contextVolume) Import(context.Context, string, io.Reader) error { return nil }
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	context := func(assert *WithT) string {
+		dir := t.TempDir()
+
+		for name, contents := range map[string]string{
+			"app/Dockerfile": "FROM busybox",
+			"app/main.go":    "package main",
+			"other/file":     "ignored",
+		} {
+			path := filepath.Join(dir, name)
+			assert.Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+			assert.Expect(os.WriteFile(path, []byte(contents), 0o600)).To(Succeed())
+		}
+
+		return dir
+	}
+
+	t.Run("a host path relative to the pipeline", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		driver := &builder{}
+		runner := runtime.NewPipelineRunner(driver, context(assert), false)
+
+		result := runner.Build(runtime.BuildInput{
+			Context: runtime.BuildContextInput{HostPath: ".", Path: "app"},
+			Tag:     "tool",
+		})
+		assert.Expect(result.Error).To(BeEmpty())
+		assert.Expect(result.Image).To(Equal("built:tool"))
+		assert.Expect(driver.files).To(ConsistOf("Dockerfile", "main.go"))
+
+		result = runner.Build(runtime.BuildInput{
+			Context: runtime.BuildContextInput{HostPath: ".", Path: "../escape"},
+		})
+		assert.Expect(result.Error).To(ContainSubstring(archive.ErrInvalidPath.Error()))
+	})
+
+	t.Run("a volume the pipeline created", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		driver := &builder{volume: context(assert)}
+		runner := runtime.NewPipelineRunner(driver, t.TempDir(), false)
+
+		result := runner.Build(runtime.BuildInput{
+			Context: runtime.BuildContextInput{Volume: "source", Path: "app"},
+		})
+		assert.Expect(result.Error).To(ContainSubstring(runtime.ErrVolumeNotFound.Error()))
+		assert.Expect(driver.created).To(BeEmpty())
+
+		volume := runner.Volume(runtime.VolumeInput{Name: "source"})
+		assert.Expect(volume.Error).To(BeEmpty())
+
+		result = runner.Build(runtime.BuildInput{
+			Context: runtime.BuildContextInput{Volume: "source", Path: "app"},
+		})
+		assert.Expect(result.Error).To(BeEmpty())
+		assert.Expect(driver.files).To(ConsistOf("Dockerfile", "main.go"))
+	})
+
+	t.Run("requires a volume or a host path", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		driver := &builder{}
+		runner := runtime.NewPipelineRunner(driver, t.TempDir(), false)
+
+		result := runner.Build(runtime.BuildInput{})
+		assert.Expect(result.Error).To(ContainSubstring(runtime.ErrBuildContextRequired.Error()))
+
+		result = runner.Build(runtime.BuildInput{
+			Context: runtime.BuildContextInput{HostPath: ".", Volume: "source"},
+		})
+		assert.Expect(result.Error).To(ContainSubstring(runtime.ErrBuildContextRequired.Error()))
+	})
+
+	t.Run("requires a driver that builds images", func(t *testing.T) {
+		t.Parallel()
+
+		assert := NewGomegaWithT(t)
+
+		runner := runtime.NewPipelineRunner(&puller{}, t.TempDir(), false)
+
+		result := runner.Build(runtime.BuildInput{Context: runtime.BuildContextInput{HostPath: "."}})
+		assert.Expect(result.Error).To(ContainSubstring(orchestra.ErrUnsupportedCapability.Error()))
+	})
+}
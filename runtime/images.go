@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/jtarchie/ci/orchestra"
+)
+
+// ImageInput is an image the pipeline exports, to be pulled before its first task starts.
+// It has the pull policy and credentials of the tasks that use it.
+type ImageInput struct {
+	Image        string             `js:"image"         json:"image"`
+	PullPolicy   string             `js:"pull_policy"   json:"pull_policy"`
+	RegistryAuth *RegistryAuthInput `js:"registry_auth" json:"registry_auth"`
+}
+
+// Prefetch pulls the images a pipeline declares concurrently, before its first task starts.
+// A failed pull is only logged, the task that uses the image reports the error when it runs.
+func (c *PipelineRunner) Prefetch(images []ImageInput) {
+	puller, ok := c.client.(orchestra.ImagePuller)
+	if !ok || !c.client.Capabilities().Images {
+		return
+	}
+
+	logger := c.log.With("orchestrator", c.client.Name())
+
+	tasks := c.prefetchTasks(images, logger)
+
+	names := []string{}
+	for _, task := range tasks {
+		names = append(names, task.Image)
+	}
+
+	logger.Info("images.prefetch", "images", names)
+
+	var waitGroup sync.WaitGroup
+
+	for _, task := range tasks {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			err := puller.PullImage(context.Background(), task)
+			if err != nil {
+				logger.Warn("images.prefetch.failed", "image", task.Image, "err", err)
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+}
+
+// prefetchTasks are the distinct images to pull, as they would be for a task.
+// Archives on the host are relative to the pipeline, like they are for tasks.
+func (c *PipelineRunner) prefetchTasks(images []ImageInput, logger *slog.Logger) []orchestra.Task {
+	type key struct {
+		auth   orchestra.RegistryAuth
+		image  string
+		policy orchestra.PullPolicy
+	}
+
+	seen := map[key]bool{}
+	tasks := []orchestra.Task{}
+
+	for _, input := range images {
+		if input.Image == "" {
+			continue
+		}
+
+		image, err := c.image(input.Image)
+		if err != nil {
+			logger.Warn("images.prefetch.failed", "image", input.Image, "err", err)
+
+			continue
+		}
+
+		policy, err := orchestra.ParsePullPolicy(input.PullPolicy)
+		if err != nil {
+			logger.Warn("images.prefetch.failed", "image", input.Image, "err", err)
+
+			continue
+		}
+
+		task := orchestra.Task{
+			Image:        image,
+			PullPolicy:   policy,
+			RegistryAuth: input.RegistryAuth.auth(),
+		}
+
+		id := key{image: image, policy: policy}
+		if task.RegistryAuth != nil {
+			id.auth = *task.RegistryAuth
+		}
+
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
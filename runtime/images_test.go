@@ -0,0 +1,74 @@
+package runtime_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/runtime"
+	. "github.com/onsi/gomega"
+)
+
+// puller is a driver that only records the images it is asked to pull.
+type puller struct {
+	mutex sync.Mutex
+	tasks []orchestra.Task
+}
+
+func (p *puller) Capabilities() orchestra.Capabilities {
+	return orchestra.Capabilities{Images: true}
+}
+
+func (p *puller) Close() error { return nil }
+
+func (p *puller) CreateVolume(context.Context, string, int) (orchestra.Volume, error) {
+	return nil, orchestra.ErrUnsupportedCapability
+}
+
+func (p *puller) Name() string { return "puller" }
+
+func (p *puller) RunContainer(context.Context, orchestra.Task) (orchestra.Container, error) {
+	return nil, orchestra.ErrUnsupportedCapability
+}
+
+func (p *puller) PullImage(_ context.Context, task orchestra.Task) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.tasks = append(p.tasks, task)
+
+	return nil
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+
+	assert := NewGomegaWithT(t)
+
+	driver := &puller{}
+	runner := runtime.NewPipelineRunner(driver, "/pipelines", false)
+
+	err := runtime.NewJS().Execute(`
+export const images = [
+  "busybox",
+  "busybox",
+  { image: "private.example.com/image", pull_policy: "always", registry_auth: { username: "user", password: "pass" } },
+  { image: "oci-archive:images/app.tar" },
+  { image: "invalid", pull_policy: "sometimes" },
+];
+
+export const pipeline = () => {};
+`, runner)
+	assert.Expect(err).NotTo(HaveOccurred())
+
+	assert.Expect(driver.tasks).To(ConsistOf(
+		orchestra.Task{Image: "busybox"},
+		orchestra.Task{
+			Image:        "private.example.com/image",
+			PullPolicy:   orchestra.PullAlways,
+			RegistryAuth: &orchestra.RegistryAuth{Username: "user", Password: "pass"},
+		},
+		orchestra.Task{Image: "oci-archive:/pipelines/images/app.tar"},
+	))
+}
@@ -34,6 +34,21 @@ func (j *JS) Execute(source string, sandbox *PipelineRunner) error {
 		return fmt.Errorf("could not set run: %w", err)
 	}
 
+	err = jsVM.Set("service", sandbox.Service)
+	if err != nil {
+		return fmt.Errorf("could not set service: %w", err)
+	}
+
+	err = jsVM.Set("build", sandbox.Build)
+	if err != nil {
+		return fmt.Errorf("could not set build: %w", err)
+	}
+
+	err = jsVM.Set("volume", sandbox.Volume)
+	if err != nil {
+		return fmt.Errorf("could not set volume: %w", err)
+	}
+
 	result := api.Transform(source, api.TransformOptions{
 		Loader:    api.LoaderTS,
 		Format:    api.FormatCommonJS,
@@ -52,26 +67,40 @@ func (j *JS) Execute(source string, sandbox *PipelineRunner) error {
 
 	program, err := goja.Compile(
 		"main.js",
-		"{(function() { const module = {}; "+string(result.Code)+"; return module.exports.pipeline;}).apply(undefined)}",
+		"{(function() { const module = {}; "+string(result.Code)+"; return module.exports;}).apply(undefined)}",
 		true,
 	)
 	if err != nil {
 		return fmt.Errorf("could not compile: %w", err)
 	}
 
-	pipeline, err := jsVM.RunProgram(program)
+	exports, err := jsVM.RunProgram(program)
 	if err != nil {
 		defer jsVM.ClearInterrupt()
 
 		return fmt.Errorf("could not run program: %w", err)
 	}
 
+	if goja.IsUndefined(exports) || goja.IsNull(exports) {
+		return ErrPipelineNotFunction
+	}
+
 	// let's run the pipeline
-	pipelineFunc, ok := goja.AssertFunction(pipeline)
+	pipelineFunc, ok := goja.AssertFunction(exports.ToObject(jsVM).Get("pipeline"))
 	if !ok {
 		return ErrPipelineNotFunction
 	}
 
+	// images can be exported, so they are pulled before the pipeline starts
+	if value := exports.ToObject(jsVM).Get("images"); value != nil && !goja.IsUndefined(value) {
+		images, err := exportImages(jsVM, value)
+		if err != nil {
+			return err
+		}
+
+		sandbox.Prefetch(images)
+	}
+
 	_, err = pipelineFunc(goja.Undefined())
 	if err != nil {
 		return fmt.Errorf("could not run pipeline: %w", err)
@@ -81,3 +110,35 @@ func (j *JS) Execute(source string, sandbox *PipelineRunner) error {
 }
 
 var ErrPipelineNotFunction = errors.New("pipeline is not a function")
+
+// exportImages reads the exported images, each is a name or an object with the pull policy
+// and credentials of the tasks that use it.
+func exportImages(jsVM *goja.Runtime, value goja.Value) ([]ImageInput, error) {
+	var values []goja.Value
+
+	err := jsVM.ExportTo(value, &values)
+	if err != nil {
+		return nil, fmt.Errorf("could not read images: %w", err)
+	}
+
+	images := []ImageInput{}
+
+	for _, value := range values {
+		if name, ok := value.Export().(string); ok {
+			images = append(images, ImageInput{Image: name})
+
+			continue
+		}
+
+		var image ImageInput
+
+		err = jsVM.ExportTo(value, &image)
+		if err != nil {
+			return nil, fmt.Errorf("could not read image: %w", err)
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
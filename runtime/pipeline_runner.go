@@ -2,39 +2,223 @@ package runtime
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jtarchie/ci/orchestra"
 )
 
 type PipelineRunner struct {
-	log    *slog.Logger
-	client orchestra.Driver
+	log           *slog.Logger
+	client        orchestra.Driver
+	dir           string
+	keepOnFailure bool
+	kept          []KeptContainer
+	services      []*Service
+	summaries     []TaskSummary
+	// volumes are the names of the volumes created so far, by volume() or the mounts of a task
+	volumes map[string]bool
 }
 
+// KeptContainer is the container of a failed task that was not cleaned up, so it can be hijacked.
+type KeptContainer struct {
+	ID   string
+	Name string
+}
+
+// NewPipelineRunner creates a runner for the pipeline, host paths are relative to dir,
+// the directory of the pipeline file. keepOnFailure leaves the containers of failed tasks behind.
 func NewPipelineRunner(
 	client orchestra.Driver,
+	dir string,
+	keepOnFailure bool,
 ) *PipelineRunner {
 	return &PipelineRunner{
-		log:    slog.Default().WithGroup("pipeline.runner"),
-		client: client,
+		log:           slog.Default().WithGroup("pipeline.runner"),
+		client:        client,
+		dir:           dir,
+		keepOnFailure: keepOnFailure,
+		volumes:       map[string]bool{},
+	}
+}
+
+// hostPath resolves a path on the host, relative to the directory of the pipeline.
+func (c *PipelineRunner) hostPath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.dir, path)
+	}
+
+	resolved, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve host path: %w", err)
 	}
+
+	return resolved, nil
+}
+
+// Kept are the containers of failed tasks that were kept.
+func (c *PipelineRunner) Kept() []KeptContainer {
+	return c.kept
+}
+
+// Summaries are the tasks that have run, in the order they finished.
+func (c *PipelineRunner) Summaries() []TaskSummary {
+	return c.summaries
 }
 
 type Result struct {
-	Code   int    `js:"code"   json:"code"`
-	Error  string `js:"error"  json:"error"`
-	Stderr string `js:"stderr" json:"stderr"`
-	Stdout string `js:"stdout" json:"stdout"`
+	Code int `js:"code" json:"code"`
+	// Duration is how long the task ran for, in seconds.
+	Duration  float64 `js:"duration"   json:"duration"`
+	Error     string  `js:"error"      json:"error"`
+	OOMKilled bool    `js:"oom_killed" json:"oom_killed"`
+	// State is the final state of the container, such as "succeeded" or "timed-out".
+	State  orchestra.State `js:"state"  json:"state"`
+	Stderr string          `js:"stderr" json:"stderr"`
+	Stdout string          `js:"stdout" json:"stdout"`
+	// Usage is nil when the driver does not measure it.
+	Usage *UsageResult `js:"usage" json:"usage"`
+}
+
+// UsageResult is what a task consumed, the cpu time is in seconds and the rest in bytes.
+type UsageResult struct {
+	CPUTime    float64 `js:"cpu_time"    json:"cpu_time"`
+	PeakMemory uint64  `js:"peak_memory" json:"peak_memory"`
+	ReadBytes  uint64  `js:"read_bytes"  json:"read_bytes"`
+	WriteBytes uint64  `js:"write_bytes" json:"write_bytes"`
+}
+
+// TaskSummary is how a task of the pipeline went, for the summary of the run.
+type TaskSummary struct {
+	Duration float64
+	Name     string
+	State    orchestra.State
+	Usage    *UsageResult
+}
+
+type ResourcesInput struct {
+	CPU    float64 `js:"cpu"    json:"cpu"`
+	Memory int64   `js:"memory" json:"memory"`
+	Pids   int64   `js:"pids"   json:"pids"`
+}
+
+// hostImagePrefixes mark images that are a path on the host, such as an archive to load,
+// rather than a reference to a registry.
+var hostImagePrefixes = []string{"oci-archive:", "docker-archive:", "rootfs:"}
+
+// image resolves the path of an image on the host, relative to the directory of the pipeline.
+func (c *PipelineRunner) image(image string) (string, error) {
+	for _, prefix := range hostImagePrefixes {
+		if path, found := strings.CutPrefix(image, prefix); found {
+			resolved, err := c.hostPath(path)
+			if err != nil {
+				return "", err
+			}
+
+			return prefix + resolved, nil
+		}
+	}
+
+	return image, nil
+}
+
+// MountInput is a named volume, the size is in bytes.
+// The type can also be "tmpfs" for scratch space, or "host" for the host path,
+// relative to the directory of the pipeline. Host mounts are read-only by default.
+type MountInput struct {
+	HostPath string `js:"host_path" json:"host_path"`
+	Name     string `js:"name"      json:"name"`
+	Path     string `js:"path"      json:"path"`
+	ReadOnly *bool  `js:"read_only" json:"read_only"`
+	Size     int    `js:"size"      json:"size"`
+	Type     string `js:"type"      json:"type"`
+}
+
+func (c *PipelineRunner) mount(m MountInput) (orchestra.Mount, error) {
+	mount := orchestra.Mount{
+		HostPath: m.HostPath,
+		Name:     m.Name,
+		Path:     m.Path,
+		Size:     m.Size,
+		Type:     orchestra.MountType(m.Type),
+	}
+
+	if mount.HostPath != "" {
+		hostPath, err := c.hostPath(mount.HostPath)
+		if err != nil {
+			return mount, err
+		}
+
+		mount.HostPath = hostPath
+	}
+
+	mount.ReadOnly = mount.Kind() == orchestra.MountTypeHost
+	if m.ReadOnly != nil {
+		mount.ReadOnly = *m.ReadOnly
+	}
+
+	return mount, nil
+}
+
+// RegistryAuthInput are credentials for the registry of the image.
+type RegistryAuthInput struct {
+	Password string `js:"password" json:"password"`
+	Username string `js:"username" json:"username"`
+}
+
+// MarshalJSON redacts the password, so it does not end up in logs.
+func (r RegistryAuthInput) MarshalJSON() ([]byte, error) {
+	//nolint:wrapcheck
+	return json.Marshal(map[string]string{
+		"password": "[redacted]",
+		"username": r.Username,
+	})
+}
+
+func (r *RegistryAuthInput) auth() *orchestra.RegistryAuth {
+	if r == nil || r.Username == "" {
+		return nil
+	}
+
+	return &orchestra.RegistryAuth{
+		Password: r.Password,
+		Username: r.Username,
+	}
 }
 
 type RunInput struct {
-	Command []string `js:"command" json:"command"`
-	Image   string   `js:"image"   json:"image"`
-	Name    string   `js:"name"    json:"name"`
+	Command    []string          `js:"command"     json:"command"`
+	Env        map[string]string `js:"env"         json:"env"`
+	Image      string            `js:"image"       json:"image"`
+	Mounts     []MountInput      `js:"mounts"      json:"mounts"`
+	Name       string            `js:"name"        json:"name"`
+	PullPolicy string            `js:"pull_policy" json:"pull_policy"`
+	// RegistryAuth overrides the credentials the driver finds, like the docker config file.
+	RegistryAuth *RegistryAuthInput `js:"registry_auth" json:"registry_auth"`
+	Resources    ResourcesInput     `js:"resources"     json:"resources"`
+	// Stdin is given to the command, such as the JSON payload of a resource script.
+	Stdin string `js:"stdin" json:"stdin"`
+	// Timeout is a duration like "10m", the task is killed when it runs for longer.
+	Timeout string `js:"timeout" json:"timeout"`
+}
+
+// Close stops the services that are still running at the end of the pipeline.
+func (c *PipelineRunner) Close() error {
+	errs := []error{}
+
+	for _, service := range c.services {
+		errs = append(errs, service.Stop())
+	}
+
+	c.services = nil
+
+	return errors.Join(errs...)
 }
 
 func (c *PipelineRunner) Run(input RunInput) *Result {
@@ -52,14 +236,80 @@ func (c *PipelineRunner) Run(input RunInput) *Result {
 
 	logger.Info("container.run", "input", input)
 
-	container, err := c.client.RunContainer(
-		ctx,
-		orchestra.Task{
-			ID:      fmt.Sprintf("%s-%s", input.Name, taskID.String()),
-			Image:   input.Image,
-			Command: input.Command,
+	pullPolicy, err := orchestra.ParsePullPolicy(input.PullPolicy)
+	if err != nil {
+		return &Result{
+			Code:  1,
+			Error: fmt.Sprintf("could not parse pull policy: %s", err),
+		}
+	}
+
+	image, err := c.image(input.Image)
+	if err != nil {
+		return &Result{
+			Code:  1,
+			Error: err.Error(),
+		}
+	}
+
+	var timeout time.Duration
+	if input.Timeout != "" {
+		timeout, err = time.ParseDuration(input.Timeout)
+		if err != nil {
+			return &Result{
+				Code:  1,
+				Error: fmt.Sprintf("could not parse timeout: %s", err),
+			}
+		}
+	}
+
+	task := orchestra.Task{
+		ID:           fmt.Sprintf("%s-%s", input.Name, taskID.String()),
+		Image:        image,
+		Command:      input.Command,
+		Env:          input.Env,
+		PullPolicy:   pullPolicy,
+		RegistryAuth: input.RegistryAuth.auth(),
+		Resources: orchestra.Resources{
+			CPU:    input.Resources.CPU,
+			Memory: input.Resources.Memory,
+			Pids:   input.Resources.Pids,
 		},
-	)
+		Timeout: timeout,
+	}
+
+	if input.Stdin != "" {
+		task.Stdin = strings.NewReader(input.Stdin)
+	}
+
+	for _, input := range input.Mounts {
+		mount, err := c.mount(input)
+		if err != nil {
+			return &Result{
+				Code:  1,
+				Error: err.Error(),
+			}
+		}
+
+		task.Mounts = append(task.Mounts, mount)
+	}
+
+	capabilities := c.client.Capabilities()
+
+	err = capabilities.Validate(task)
+	if err != nil {
+		return &Result{
+			Code:  1,
+			Error: fmt.Sprintf("could not run container on %s: %s", c.client.Name(), err),
+		}
+	}
+
+	// only drivers told to ignore images get this far with one
+	if task.Image != "" && !capabilities.SupportsImage(task.Image) {
+		logger.Warn("container.image.ignored", "image", task.Image)
+	}
+
+	container, err := c.client.RunContainer(ctx, task)
 	if err != nil {
 		return &Result{
 			Code:  1,
@@ -67,6 +317,12 @@ func (c *PipelineRunner) Run(input RunInput) *Result {
 		}
 	}
 
+	for _, mount := range task.Mounts {
+		if mount.Kind() == orchestra.MountTypeVolume {
+			c.volumes[mount.Name] = true
+		}
+	}
+
 	var status orchestra.ContainerStatus
 
 	for {
@@ -85,30 +341,86 @@ func (c *PipelineRunner) Run(input RunInput) *Result {
 		}
 	}
 
-	logger.Info("container.status", "exitCode", status.ExitCode())
+	logger.Info(
+		"container.status",
+		"state", status.State(),
+		"reason", status.Reason(),
+		"exitCode", status.ExitCode(),
+		"oomKilled", status.IsOOMKilled(),
+	)
 
 	defer func() {
+		if c.keepOnFailure && status.ExitCode() != 0 {
+			logger.Info("container.kept", "containerID", container.ID())
+
+			c.kept = append(c.kept, KeptContainer{ID: container.ID(), Name: input.Name})
+
+			return
+		}
+
 		err := container.Cleanup(ctx)
 		if err != nil {
 			slog.Error("container.cleanup", "err", err)
 		}
 	}()
 
+	result := &Result{
+		Code:      status.ExitCode(),
+		Duration:  duration(status).Seconds(),
+		OOMKilled: status.IsOOMKilled(),
+		State:     status.State(),
+	}
+
+	switch status.State() {
+	case orchestra.StateErrored, orchestra.StateTimedOut:
+		result.Error = status.Reason()
+	case orchestra.StatePending, orchestra.StateRunning, orchestra.StateSucceeded,
+		orchestra.StateFailed, orchestra.StateOOMKilled:
+	}
+
+	if reporter, ok := container.(orchestra.UsageReporter); ok {
+		usage, err := reporter.Usage(ctx)
+		if err != nil {
+			logger.Warn("container.usage", "err", err)
+		} else {
+			result.Usage = &UsageResult{
+				CPUTime:    usage.CPUTime.Seconds(),
+				PeakMemory: usage.PeakMemory,
+				ReadBytes:  usage.ReadBytes,
+				WriteBytes: usage.WriteBytes,
+			}
+		}
+	}
+
+	c.summaries = append(c.summaries, TaskSummary{
+		Duration: result.Duration,
+		Name:     input.Name,
+		State:    result.State,
+		Usage:    result.Usage,
+	})
+
 	stdout, stderr := &strings.Builder{}, &strings.Builder{}
 
 	err = container.Logs(ctx, stdout, stderr)
 	if err != nil {
 		logger.Error("container.logs", "err", err)
 
-		return &Result{
-			Code:  status.ExitCode(),
-			Error: fmt.Sprintf("could not get container logs: %s", err),
-		}
+		result.Error = fmt.Sprintf("could not get container logs: %s", err)
+
+		return result
 	}
 
-	return &Result{
-		Stdout: stdout.String(),
-		Stderr: stderr.String(),
-		Code:   status.ExitCode(),
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	return result
+}
+
+// duration is how long the container ran for, zero when it never started.
+func duration(status orchestra.ContainerStatus) time.Duration {
+	if status.StartedAt().IsZero() || status.FinishedAt().IsZero() {
+		return 0
 	}
+
+	return status.FinishedAt().Sub(status.StartedAt())
 }
@@ -0,0 +1,258 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jtarchie/ci/orchestra"
+)
+
+type HTTPGetInput struct {
+	Path string `js:"path" json:"path"`
+	Port int    `js:"port" json:"port"`
+}
+
+// ProbeInput describes a readiness probe, durations are strings like "500ms" or "2s".
+type ProbeInput struct {
+	Exec     []string      `js:"exec"     json:"exec"`
+	HTTPGet  *HTTPGetInput `js:"http_get" json:"http_get"`
+	Interval string        `js:"interval" json:"interval"`
+	Retries  int           `js:"retries"  json:"retries"`
+	TCPPort  int           `js:"tcp_port" json:"tcp_port"`
+	Timeout  string        `js:"timeout"  json:"timeout"`
+}
+
+func (p *ProbeInput) probe() (*orchestra.Probe, error) {
+	if p == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	probe := &orchestra.Probe{
+		Exec:    p.Exec,
+		Retries: p.Retries,
+		TCPPort: p.TCPPort,
+	}
+
+	if p.HTTPGet != nil {
+		probe.HTTPGet = &orchestra.HTTPGet{
+			Path: p.HTTPGet.Path,
+			Port: p.HTTPGet.Port,
+		}
+	}
+
+	var err error
+
+	if p.Interval != "" {
+		probe.Interval, err = time.ParseDuration(p.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse interval: %w", err)
+		}
+	}
+
+	if p.Timeout != "" {
+		probe.Timeout, err = time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse timeout: %w", err)
+		}
+	}
+
+	return probe, nil
+}
+
+type ServiceInput struct {
+	Command    []string          `js:"command"     json:"command"`
+	Env        map[string]string `js:"env"         json:"env"`
+	Image      string            `js:"image"       json:"image"`
+	Name       string            `js:"name"        json:"name"`
+	Ports      []int             `js:"ports"       json:"ports"`
+	PullPolicy string            `js:"pull_policy" json:"pull_policy"`
+	Readiness  *ProbeInput       `js:"readiness"   json:"readiness"`
+	// RegistryAuth overrides the credentials the driver finds, like the docker config file.
+	RegistryAuth *RegistryAuthInput `js:"registry_auth" json:"registry_auth"`
+}
+
+// Service is a running service as seen by the pipeline.
+// Ports maps the ports the service listens on to where other tasks reach them.
+type Service struct {
+	Error string         `js:"error" json:"error"`
+	Host  string         `js:"host"  json:"host"`
+	Name  string         `js:"name"  json:"name"`
+	Ports map[string]int `js:"ports" json:"ports"`
+
+	container orchestra.ServiceContainer
+}
+
+// Stop removes the service, it is safe to call more than once.
+func (s *Service) Stop() error {
+	if s.container == nil {
+		return nil
+	}
+
+	err := s.container.Cleanup(context.Background())
+	s.container = nil
+
+	if err != nil {
+		return fmt.Errorf("could not stop service %s: %w", s.Name, err)
+	}
+
+	return nil
+}
+
+// Exec runs a command in the service, such as a database migration.
+func (s *Service) Exec(command []string) *Result {
+	if s.container == nil {
+		return &Result{
+			Code:  1,
+			Error: fmt.Sprintf("could not exec in service %s: it is not running", s.Name),
+		}
+	}
+
+	stdout, stderr := &strings.Builder{}, &strings.Builder{}
+
+	code, err := s.container.Exec(context.Background(), command, nil, stdout, stderr)
+	if err != nil {
+		return &Result{
+			Code:   1,
+			Error:  fmt.Sprintf("could not exec in service %s: %s", s.Name, err),
+			Stderr: stderr.String(),
+			Stdout: stdout.String(),
+		}
+	}
+
+	return &Result{
+		Code:   code,
+		Stderr: stderr.String(),
+		Stdout: stdout.String(),
+	}
+}
+
+func (c *PipelineRunner) Service(input ServiceInput) *Service {
+	ctx := context.Background()
+
+	serviceID, err := uuid.NewV7()
+	if err != nil {
+		return &Service{Error: fmt.Sprintf("could not generate uuid: %s", err)}
+	}
+
+	logger := c.log.With("id", serviceID, "orchestrator", c.client.Name())
+
+	logger.Info("service.run", "input", input)
+
+	driver, ok := c.client.(orchestra.ServiceDriver)
+	if !ok {
+		return &Service{
+			Error: fmt.Sprintf("could not run service on %s: services are %s", c.client.Name(), orchestra.ErrUnsupportedCapability),
+		}
+	}
+
+	readiness, err := input.Readiness.probe()
+	if err != nil {
+		return &Service{Error: fmt.Sprintf("could not parse readiness: %s", err)}
+	}
+
+	pullPolicy, err := orchestra.ParsePullPolicy(input.PullPolicy)
+	if err != nil {
+		return &Service{Error: fmt.Sprintf("could not parse pull policy: %s", err)}
+	}
+
+	image, err := c.image(input.Image)
+	if err != nil {
+		return &Service{Error: err.Error()}
+	}
+
+	service := orchestra.Service{
+		Task: orchestra.Task{
+			ID:           fmt.Sprintf("%s-%s", input.Name, serviceID.String()),
+			Image:        image,
+			Command:      input.Command,
+			Env:          input.Env,
+			PullPolicy:   pullPolicy,
+			Readiness:    readiness,
+			RegistryAuth: input.RegistryAuth.auth(),
+		},
+		Name:  input.Name,
+		Ports: input.Ports,
+	}
+
+	err = c.client.Capabilities().ValidateService(service)
+	if err != nil {
+		return &Service{
+			Error: fmt.Sprintf("could not run service on %s: %s", c.client.Name(), err),
+		}
+	}
+
+	container, err := driver.RunService(ctx, service)
+	if err != nil {
+		return &Service{Error: fmt.Sprintf("could not run service: %s", err)}
+	}
+
+	err = waitUntilReady(ctx, container, readiness)
+	if err != nil {
+		logger.Error("service.ready", "err", err)
+
+		_ = container.Cleanup(ctx)
+
+		return &Service{Error: fmt.Sprintf("could not wait for service: %s", err)}
+	}
+
+	logger.Info("service.ready")
+
+	ports := map[string]int{}
+	for _, port := range input.Ports {
+		ports[strconv.Itoa(port)] = container.Port(port)
+	}
+
+	result := &Service{
+		Host:      container.Host(),
+		Name:      input.Name,
+		Ports:     ports,
+		container: container,
+	}
+
+	c.services = append(c.services, result)
+
+	return result
+}
+
+var (
+	ErrServiceExited   = errors.New("service exited before it was ready")
+	ErrServiceNotReady = errors.New("service was not ready in time")
+)
+
+// waitUntilReady polls the status of the service until it is ready,
+// so the steps that depend on it are not started too early.
+func waitUntilReady(ctx context.Context, container orchestra.Container, readiness *orchestra.Probe) error {
+	interval := orchestra.DefaultProbeInterval / 10
+	deadline := time.Now().Add(orchestra.Probe{}.Deadline())
+
+	if readiness != nil {
+		interval = readiness.WithDefaults().Interval / 2
+		deadline = time.Now().Add(readiness.Deadline())
+	}
+
+	for {
+		status, err := container.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get service status: %w", err)
+		}
+
+		if status.IsReady() {
+			return nil
+		}
+
+		if status.IsDone() {
+			return fmt.Errorf("%w: %s with exit code %d", ErrServiceExited, status.State(), status.ExitCode())
+		}
+
+		if time.Now().After(deadline) {
+			return ErrServiceNotReady
+		}
+
+		time.Sleep(interval)
+	}
+}
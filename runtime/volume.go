@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jtarchie/ci/orchestra"
+	"github.com/jtarchie/ci/orchestra/archive"
+)
+
+// VolumeInput is a named volume, the size is in bytes.
+type VolumeInput struct {
+	Name string `js:"name" json:"name"`
+	Size int    `js:"size" json:"size"`
+}
+
+// Volume is a volume as seen by the pipeline.
+// It is the same volume tasks get when they mount it by name.
+type Volume struct {
+	Error string `js:"error" json:"error"`
+	Name  string `js:"name"  json:"name"`
+
+	runner *PipelineRunner
+	volume orchestra.Volume
+}
+
+var ErrVolumeNotCreated = errors.New("volume was not created")
+
+// Import copies the contents of a host directory into path within the volume.
+// The host directory is relative to the pipeline, like host mounts.
+func (v *Volume) Import(hostPath string, path string) error {
+	if v.volume == nil {
+		return fmt.Errorf("%w: %s", ErrVolumeNotCreated, v.Error)
+	}
+
+	hostPath, err := v.runner.hostPath(hostPath)
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(archive.Tar(hostPath, writer, nil))
+	}()
+
+	err = v.volume.Import(context.Background(), path, reader)
+	_ = reader.Close()
+
+	if err != nil {
+		return fmt.Errorf("could not import %s into volume %s: %w", hostPath, v.Name, err)
+	}
+
+	return nil
+}
+
+// Export copies the contents of path within the volume into a host directory,
+// which is relative to the pipeline.
+func (v *Volume) Export(path string, hostPath string) error {
+	if v.volume == nil {
+		return fmt.Errorf("%w: %s", ErrVolumeNotCreated, v.Error)
+	}
+
+	hostPath, err := v.runner.hostPath(hostPath)
+	if err != nil {
+		return err
+	}
+
+	reader, err := v.volume.Export(context.Background(), path)
+	if err != nil {
+		return fmt.Errorf("could not export %s from volume %s: %w", path, v.Name, err)
+	}
+	defer reader.Close()
+
+	err = os.MkdirAll(hostPath, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", hostPath, err)
+	}
+
+	err = archive.Untar(reader, hostPath)
+	if err != nil {
+		return fmt.Errorf("could not export %s from volume %s: %w", path, v.Name, err)
+	}
+
+	return nil
+}
+
+func (c *PipelineRunner) Volume(input VolumeInput) *Volume {
+	logger := c.log.With("orchestrator", c.client.Name())
+
+	logger.Info("volume.create", "input", input)
+
+	if !c.client.Capabilities().Volumes {
+		return &Volume{
+			Error: fmt.Sprintf("could not create volume on %s: volumes are %s", c.client.Name(), orchestra.ErrUnsupportedCapability),
+			Name:  input.Name,
+		}
+	}
+
+	volume, err := c.client.CreateVolume(context.Background(), input.Name, input.Size)
+	if err != nil {
+		return &Volume{
+			Error: fmt.Sprintf("could not create volume: %s", err),
+			Name:  input.Name,
+		}
+	}
+
+	c.volumes[input.Name] = true
+
+	return &Volume{
+		Name:   input.Name,
+		runner: c,
+		volume: volume,
+	}
+}